@@ -0,0 +1,32 @@
+package geoip
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/twitchscience/scoop_protocol/transformer"
+)
+
+func TestCache(t *testing.T) {
+	c := newCache(2)
+	a := &transformer.GeoRecord{City: "a"}
+	b := &transformer.GeoRecord{City: "b"}
+
+	c.add("a", a)
+	c.add("b", b)
+	record, ok := c.get("a")
+	assert.True(t, ok)
+	assert.Equal(t, a, record)
+
+	// b is now the least recently used
+	c.add("c", nil)
+	_, ok = c.get("b")
+	assert.False(t, ok)
+	record, ok = c.get("c")
+	assert.True(t, ok, "nil records are cached")
+	assert.Nil(t, record)
+
+	c.purge()
+	_, ok = c.get("a")
+	assert.False(t, ok)
+}
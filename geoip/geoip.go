@@ -0,0 +1,283 @@
+// Package geoip implements transformer.GeoLookup on top of local MaxMind DB files, such
+// as GeoIP2-City and GeoLite2-ASN.
+package geoip
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/oschwald/maxminddb-golang"
+	"github.com/twitchscience/scoop_protocol/transformer"
+)
+
+// DefaultCacheSize is the number of lookups cached when Config.CacheSize is 0.
+const DefaultCacheSize = 10000
+
+// Config describes the databases a Reader uses and how it maintains them.
+type Config struct {
+	// CityPath is the database with city, subdivision and country data.
+	CityPath string
+	// ASNPath is the database with autonomous system data. It is optional.
+	ASNPath string
+	// CacheSize is the number of recent lookups to remember. 0 means DefaultCacheSize
+	// and a negative size disables the cache.
+	CacheSize int
+	// ReloadInterval is how often the files are checked for changes. 0 disables
+	// reloading.
+	ReloadInterval time.Duration
+	// OnReloadError is called when a changed file cannot be loaded. The Reader keeps
+	// using the previous version of the database.
+	OnReloadError func(error)
+}
+
+// Reader looks up IP addresses in MaxMind DB files. It is safe for concurrent use.
+type Reader struct {
+	cfg   Config
+	cache *cache
+
+	mu   sync.RWMutex
+	city *database
+	asn  *database
+
+	stop      chan struct{}
+	wg        sync.WaitGroup
+	closeOnce sync.Once
+	closeErr  error
+}
+
+// database is a MaxMind DB file loaded into memory and the state of the file when it was
+// read. The file is not memory-mapped, so it may be rewritten in place while in use.
+type database struct {
+	reader  *maxminddb.Reader
+	modTime time.Time
+	size    int64
+}
+
+type cityRecord struct {
+	City struct {
+		Names map[string]string `maxminddb:"names"`
+	} `maxminddb:"city"`
+	Subdivisions []struct {
+		Names map[string]string `maxminddb:"names"`
+	} `maxminddb:"subdivisions"`
+	Country struct {
+		ISOCode string `maxminddb:"iso_code"`
+	} `maxminddb:"country"`
+}
+
+type asnRecord struct {
+	Number       uint32 `maxminddb:"autonomous_system_number"`
+	Organization string `maxminddb:"autonomous_system_organization"`
+}
+
+// Open opens the databases described by cfg and, if cfg.ReloadInterval is set, starts
+// watching them for changes.
+func Open(cfg Config) (*Reader, error) {
+	if cfg.CityPath == "" {
+		return nil, errors.New("no city database given")
+	}
+
+	r := &Reader{cfg: cfg, stop: make(chan struct{})}
+	switch {
+	case cfg.CacheSize == 0:
+		r.cache = newCache(DefaultCacheSize)
+	case cfg.CacheSize > 0:
+		r.cache = newCache(cfg.CacheSize)
+	}
+
+	var err error
+	if r.city, err = openDatabase(cfg.CityPath); err != nil {
+		return nil, err
+	}
+	if cfg.ASNPath != "" {
+		if r.asn, err = openDatabase(cfg.ASNPath); err != nil {
+			_ = r.city.reader.Close()
+			return nil, err
+		}
+	}
+
+	if cfg.ReloadInterval > 0 {
+		r.wg.Add(1)
+		go r.watch()
+	}
+	return r, nil
+}
+
+func openDatabase(path string) (*database, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = f.Close() }()
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	b, err := ioutil.ReadAll(f)
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %v", path, err)
+	}
+	reader, err := maxminddb.FromBytes(b)
+	if err != nil {
+		return nil, fmt.Errorf("opening %s: %v", path, err)
+	}
+	return &database{reader: reader, modTime: info.ModTime(), size: info.Size()}, nil
+}
+
+// changed returns true if the file at path is no longer the one db was opened from.
+func (db *database) changed(path string) (bool, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return false, err
+	}
+	return !info.ModTime().Equal(db.modTime) || info.Size() != db.size, nil
+}
+
+func (r *Reader) watch() {
+	defer r.wg.Done()
+	ticker := time.NewTicker(r.cfg.ReloadInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if err := r.Reload(); err != nil && r.cfg.OnReloadError != nil {
+				r.cfg.OnReloadError(err)
+			}
+		case <-r.stop:
+			return
+		}
+	}
+}
+
+// Reload reopens any database whose file has changed since it was opened. On error the
+// previous databases stay in use.
+func (r *Reader) Reload() error {
+	r.mu.RLock()
+	city, asn := r.city, r.asn
+	r.mu.RUnlock()
+
+	newCity, err := reopen(city, r.cfg.CityPath)
+	if err != nil {
+		return err
+	}
+	newASN, err := reopen(asn, r.cfg.ASNPath)
+	if err != nil {
+		if newCity != city {
+			_ = newCity.reader.Close()
+		}
+		return err
+	}
+	if newCity == city && newASN == asn {
+		return nil
+	}
+
+	r.mu.Lock()
+	r.city, r.asn = newCity, newASN
+	if r.cache != nil {
+		r.cache.purge()
+	}
+	r.mu.Unlock()
+
+	if newCity != city {
+		_ = city.reader.Close()
+	}
+	if newASN != asn {
+		_ = asn.reader.Close()
+	}
+	return nil
+}
+
+// reopen returns a new database if the file at path has changed, and db otherwise.
+func reopen(db *database, path string) (*database, error) {
+	if db == nil {
+		return nil, nil
+	}
+	changed, err := db.changed(path)
+	if err != nil || !changed {
+		return db, err
+	}
+	return openDatabase(path)
+}
+
+// Lookup returns what the databases know about ip, or nil if they know nothing. The
+// returned record is shared and must not be modified.
+func (r *Reader) Lookup(ip net.IP) (*transformer.GeoRecord, error) {
+	key := string(ip.To16())
+	if len(key) != net.IPv6len {
+		return nil, fmt.Errorf("invalid IP address %v", ip)
+	}
+	if r.cache != nil {
+		if record, ok := r.cache.get(key); ok {
+			return record, nil
+		}
+	}
+
+	// the record is cached under the lock so that a concurrent Reload cannot purge the
+	// cache before a record from the old database is added
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	record, err := r.lookup(ip)
+	if err != nil {
+		return nil, err
+	}
+	if r.cache != nil {
+		r.cache.add(key, record)
+	}
+	return record, nil
+}
+
+func (r *Reader) lookup(ip net.IP) (*transformer.GeoRecord, error) {
+	var record transformer.GeoRecord
+	var city cityRecord
+	_, found, err := r.city.reader.LookupNetwork(ip, &city)
+	if err != nil {
+		return nil, fmt.Errorf("looking up %v: %v", ip, err)
+	}
+	if found {
+		record.City = city.City.Names["en"]
+		if len(city.Subdivisions) > 0 {
+			record.Region = city.Subdivisions[0].Names["en"]
+		}
+		record.Country = city.Country.ISOCode
+	}
+
+	if r.asn != nil {
+		var asn asnRecord
+		_, asnFound, err := r.asn.reader.LookupNetwork(ip, &asn)
+		if err != nil {
+			return nil, fmt.Errorf("looking up %v: %v", ip, err)
+		}
+		record.ASN = asn.Number
+		record.ASNOrganization = asn.Organization
+		found = found || asnFound
+	}
+
+	if !found {
+		return nil, nil
+	}
+	return &record, nil
+}
+
+// Close stops watching for changes and closes the databases. Calling it again does
+// nothing and returns the first call's error.
+func (r *Reader) Close() error {
+	r.closeOnce.Do(func() {
+		close(r.stop)
+		r.wg.Wait()
+
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		r.closeErr = r.city.reader.Close()
+		if r.asn != nil {
+			if err := r.asn.reader.Close(); r.closeErr == nil {
+				r.closeErr = err
+			}
+		}
+	})
+	return r.closeErr
+}
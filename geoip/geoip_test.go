@@ -0,0 +1,178 @@
+package geoip
+
+import (
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/twitchscience/scoop_protocol/transformer"
+)
+
+func TestLookup(t *testing.T) {
+	r, err := Open(Config{CityPath: "testdata/city.mmdb", ASNPath: "testdata/asn.mmdb"})
+	require.NoError(t, err)
+	defer func() { assert.NoError(t, r.Close()) }()
+
+	testCases := []struct {
+		ip       string
+		expected *transformer.GeoRecord
+	}{
+		{"81.2.69.160", &transformer.GeoRecord{
+			City: "London", Region: "England", Country: "GB", ASN: 20712, ASNOrganization: "Andrews & Arnold Ltd"}},
+		{"216.160.83.58", &transformer.GeoRecord{City: "Milton", Region: "Washington", Country: "US"}},
+		{"1.130.1.1", &transformer.GeoRecord{ASN: 1221, ASNOrganization: "Telstra Pty Ltd"}},
+		{"2001:480:10::1", &transformer.GeoRecord{
+			City: "San Diego", Region: "California", Country: "US", ASN: 668, ASNOrganization: "DoD Network Information Center"}},
+		{"8.8.8.8", nil},
+		{"::1", nil},
+	}
+	for _, tc := range testCases {
+		// the second lookup is answered by the cache
+		for i := 0; i < 2; i++ {
+			record, err := r.Lookup(net.ParseIP(tc.ip))
+			assert.NoError(t, err, tc.ip)
+			assert.Equal(t, tc.expected, record, tc.ip)
+		}
+	}
+
+	_, err = r.Lookup(net.IP{1, 2, 3})
+	assert.Error(t, err)
+}
+
+func TestTransformers(t *testing.T) {
+	r, err := Open(Config{CityPath: "testdata/city.mmdb", ASNPath: "testdata/asn.mmdb", CacheSize: -1})
+	require.NoError(t, err)
+	defer func() { assert.NoError(t, r.Close()) }()
+
+	registry := transformer.NewRegistry()
+	registry.UseGeoLookup(r)
+	expected := map[string]interface{}{
+		"ipCity":       "London",
+		"ipRegion":     "England",
+		"ipCountry":    "GB",
+		"ipAsn":        "AS20712 Andrews & Arnold Ltd",
+		"ipAsnInteger": int64(20712),
+	}
+	for name, value := range expected {
+		tr, _ := registry.Lookup(name)
+		v, err := tr.ConvertIP(net.IPv4(81, 2, 69, 160))
+		assert.NoError(t, err, name)
+		assert.Equal(t, value, v, name)
+	}
+}
+
+func copyFile(t *testing.T, src, dst string) {
+	b, err := ioutil.ReadFile(src)
+	require.NoError(t, err)
+	tmp := dst + ".tmp"
+	require.NoError(t, ioutil.WriteFile(tmp, b, 0644))
+	require.NoError(t, os.Rename(tmp, dst))
+}
+
+func TestReload(t *testing.T) {
+	dir, err := ioutil.TempDir("", "geoip")
+	require.NoError(t, err)
+	defer func() { _ = os.RemoveAll(dir) }()
+	path := filepath.Join(dir, "city.mmdb")
+	copyFile(t, "testdata/city.mmdb", path)
+
+	// without a cache every lookup goes to the database
+	r, err := Open(Config{CityPath: path, CacheSize: -1})
+	require.NoError(t, err)
+	defer func() { assert.NoError(t, r.Close()) }()
+
+	london := net.IPv4(81, 2, 69, 160)
+	record, err := r.Lookup(london)
+	require.NoError(t, err)
+	assert.Equal(t, "London", record.City)
+
+	require.NoError(t, r.Reload(), "reloading an unchanged file failed")
+
+	// rewrite the file in place rather than renaming over it
+	b, err := ioutil.ReadFile("testdata/city-updated.mmdb")
+	require.NoError(t, err)
+	require.NoError(t, ioutil.WriteFile(path, b, 0644))
+	record, err = r.Lookup(london)
+	require.NoError(t, err, "rewriting the file broke the database in use")
+	assert.Equal(t, "London", record.City)
+	require.NoError(t, r.Reload())
+	record, err = r.Lookup(london)
+	require.NoError(t, err)
+	assert.Equal(t, "Manchester", record.City)
+
+	require.NoError(t, ioutil.WriteFile(path, []byte("not a database"), 0644))
+	assert.Error(t, r.Reload())
+	record, err = r.Lookup(london)
+	require.NoError(t, err)
+	assert.Equal(t, "Manchester", record.City, "failed reload dropped the old database")
+}
+
+func TestReloadPurgesCache(t *testing.T) {
+	dir, err := ioutil.TempDir("", "geoip")
+	require.NoError(t, err)
+	defer func() { _ = os.RemoveAll(dir) }()
+	path := filepath.Join(dir, "city.mmdb")
+	copyFile(t, "testdata/city.mmdb", path)
+
+	r, err := Open(Config{CityPath: path})
+	require.NoError(t, err)
+	defer func() { assert.NoError(t, r.Close()) }()
+
+	london := net.IPv4(81, 2, 69, 160)
+	record, err := r.Lookup(london)
+	require.NoError(t, err)
+	assert.Equal(t, "London", record.City)
+
+	copyFile(t, "testdata/city-updated.mmdb", path)
+	require.NoError(t, r.Reload())
+	record, err = r.Lookup(london)
+	require.NoError(t, err)
+	assert.Equal(t, "Manchester", record.City, "cached record survived a reload")
+}
+
+func TestCloseTwice(t *testing.T) {
+	r, err := Open(Config{CityPath: "testdata/city.mmdb", ReloadInterval: time.Hour})
+	require.NoError(t, err)
+	assert.NoError(t, r.Close())
+	assert.NoError(t, r.Close())
+}
+
+func TestWatch(t *testing.T) {
+	dir, err := ioutil.TempDir("", "geoip")
+	require.NoError(t, err)
+	defer func() { _ = os.RemoveAll(dir) }()
+	path := filepath.Join(dir, "city.mmdb")
+	copyFile(t, "testdata/city.mmdb", path)
+
+	r, err := Open(Config{CityPath: path, ReloadInterval: 10 * time.Millisecond})
+	require.NoError(t, err)
+	defer func() { assert.NoError(t, r.Close()) }()
+
+	copyFile(t, "testdata/city-updated.mmdb", path)
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		record, err := r.Lookup(net.IPv4(81, 2, 69, 160))
+		require.NoError(t, err)
+		if record.City == "Manchester" {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("database was not reloaded")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestOpenErrors(t *testing.T) {
+	_, err := Open(Config{})
+	assert.Error(t, err)
+	_, err = Open(Config{CityPath: "testdata/missing.mmdb"})
+	assert.Error(t, err)
+	_, err = Open(Config{CityPath: "testdata/city.mmdb", ASNPath: "testdata/generate.go"})
+	assert.Error(t, err)
+}
@@ -0,0 +1,214 @@
+//go:build ignore
+// +build ignore
+
+// This program writes the small MaxMind DB files used by the geoip tests:
+//
+//	go run testdata/generate.go
+//
+// It implements just enough of the MaxMind DB format
+// (https://maxmind.github.io/MaxMind-DB/) to describe a handful of networks.
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"log"
+	"math"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+type network struct {
+	cidr string
+	data map[string]interface{}
+}
+
+func names(en string) map[string]interface{} {
+	return map[string]interface{}{"names": map[string]interface{}{"en": en}}
+}
+
+func city(city, region, country string) map[string]interface{} {
+	return map[string]interface{}{
+		"city":         names(city),
+		"subdivisions": []interface{}{names(region)},
+		"country":      map[string]interface{}{"iso_code": country},
+	}
+}
+
+func asn(number uint32, org string) map[string]interface{} {
+	return map[string]interface{}{
+		"autonomous_system_number":       number,
+		"autonomous_system_organization": org,
+	}
+}
+
+func main() {
+	write("city.mmdb", "GeoIP2-City", []network{
+		{"81.2.69.0/24", city("London", "England", "GB")},
+		{"216.160.83.56/29", city("Milton", "Washington", "US")},
+		{"2001:480::/32", city("San Diego", "California", "US")},
+	})
+	write("city-updated.mmdb", "GeoIP2-City", []network{
+		{"81.2.69.0/24", city("Manchester", "England", "GB")},
+	})
+	write("asn.mmdb", "GeoLite2-ASN", []network{
+		{"1.128.0.0/11", asn(1221, "Telstra Pty Ltd")},
+		{"81.2.69.0/24", asn(20712, "Andrews & Arnold Ltd")},
+		{"2001:480::/32", asn(668, "DoD Network Information Center")},
+	})
+}
+
+type node struct {
+	children [2]*node
+	data     int // offset of the data + 1, 0 if none
+}
+
+func write(path, dbType string, networks []network) {
+	var data bytes.Buffer
+	root := &node{}
+	for _, n := range networks {
+		_, ipnet, err := net.ParseCIDR(n.cidr)
+		if err != nil {
+			log.Fatal(err)
+		}
+		ones, _ := ipnet.Mask.Size()
+		ip := ipnet.IP.To16()
+		if ipnet.IP.To4() != nil {
+			// IPv4 networks live in ::/96 of an IPv6 tree.
+			ip = append(make(net.IP, 12), ipnet.IP.To4()...)
+			ones += 96
+		}
+
+		offset := data.Len()
+		encode(&data, n.data)
+
+		cur := root
+		for i := 0; i < ones; i++ {
+			bit := (ip[i/8] >> uint(7-i%8)) & 1
+			if cur.children[bit] == nil {
+				cur.children[bit] = &node{}
+			}
+			cur = cur.children[bit]
+		}
+		cur.data = offset + 1
+	}
+
+	// Number the inner nodes breadth first; leaves become records.
+	var nodes []*node
+	index := make(map[*node]int)
+	queue := []*node{root}
+	for len(queue) > 0 {
+		n := queue[0]
+		queue = queue[1:]
+		index[n] = len(nodes)
+		nodes = append(nodes, n)
+		for _, c := range n.children {
+			if c != nil && c.data == 0 {
+				queue = append(queue, c)
+			}
+		}
+	}
+
+	var out bytes.Buffer
+	count := len(nodes)
+	for _, n := range nodes {
+		for _, c := range n.children {
+			var record int
+			switch {
+			case c == nil:
+				record = count
+			case c.data != 0:
+				record = count + 16 + c.data - 1
+			default:
+				record = index[c]
+			}
+			out.Write([]byte{byte(record >> 16), byte(record >> 8), byte(record)})
+		}
+	}
+	out.Write(make([]byte, 16))
+	out.Write(data.Bytes())
+	out.WriteString("\xAB\xCD\xEFMaxMind.com")
+	encode(&out, map[string]interface{}{
+		"binary_format_major_version": uint16(2),
+		"binary_format_minor_version": uint16(0),
+		"build_epoch":                 uint64(1500000000),
+		"database_type":               dbType,
+		"description":                 map[string]interface{}{"en": "scoop_protocol test data"},
+		"ip_version":                  uint16(6),
+		"languages":                   []interface{}{"en"},
+		"node_count":                  uint32(count),
+		"record_size":                 uint16(24),
+	})
+
+	if err := os.WriteFile(filepath.Join("testdata", path), out.Bytes(), 0644); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func control(b *bytes.Buffer, typ, size int) {
+	first := 0
+	if typ <= 7 {
+		first = typ << 5
+	}
+	var ext []byte
+	switch {
+	case size < 29:
+		first |= size
+	case size < 285:
+		first |= 29
+		ext = []byte{byte(size - 29)}
+	default:
+		first |= 30
+		ext = []byte{byte((size - 285) >> 8), byte(size - 285)}
+	}
+	b.WriteByte(byte(first))
+	if typ > 7 {
+		b.WriteByte(byte(typ - 7))
+	}
+	b.Write(ext)
+}
+
+func encodeUint(b *bytes.Buffer, typ int, v uint64) {
+	var buf [8]byte
+	binary.BigEndian.PutUint64(buf[:], v)
+	trimmed := bytes.TrimLeft(buf[:], "\x00")
+	control(b, typ, len(trimmed))
+	b.Write(trimmed)
+}
+
+func encode(b *bytes.Buffer, v interface{}) {
+	switch v := v.(type) {
+	case string:
+		control(b, 2, len(v))
+		b.WriteString(v)
+	case float64:
+		control(b, 3, 8)
+		binary.Write(b, binary.BigEndian, math.Float64bits(v))
+	case uint16:
+		encodeUint(b, 5, uint64(v))
+	case uint32:
+		encodeUint(b, 6, uint64(v))
+	case uint64:
+		encodeUint(b, 9, v)
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		control(b, 7, len(keys))
+		for _, k := range keys {
+			encode(b, k)
+			encode(b, v[k])
+		}
+	case []interface{}:
+		control(b, 11, len(v))
+		for _, e := range v {
+			encode(b, e)
+		}
+	default:
+		log.Fatalf("cannot encode %T", v)
+	}
+}
@@ -0,0 +1,74 @@
+package msg_signer_test
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"fmt"
+	"hash"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/twitchscience/scoop_protocol/msg_signer"
+)
+
+// Run with -race to catch signers sharing state between goroutines.
+func TestConcurrentSigners(t *testing.T) {
+	secret := []byte("secret")
+	keyring, err := msg_signer.NewKeyring("key", secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signers := map[string]*msg_signer.TimeSigner{
+		"NewTimeSigner":        msg_signer.NewTimeSigner(hmac.New(sha256.New, secret)),
+		"NewTimeSignerFunc":    msg_signer.NewTimeSignerFunc(func() hash.Hash { return hmac.New(sha256.New, secret) }),
+		"NewKeyringTimeSigner": msg_signer.NewKeyringTimeSigner(keyring),
+	}
+
+	// a reference signer, used by one goroutine only, says what each signature must be
+	reference := msg_signer.NewSigner(hmac.New(sha256.New, secret))
+
+	for name, s := range signers {
+		var wg sync.WaitGroup
+		for g := 0; g < 16; g++ {
+			wg.Add(1)
+			go func(g int) {
+				defer wg.Done()
+				for i := 0; i < 200; i++ {
+					msg := []byte(fmt.Sprintf("goroutine %d message %d", g, i))
+					b := s.Sign(msg)
+					got, ok := s.Verify(b, time.Minute)
+					if !ok || string(got) != string(msg) {
+						t.Errorf("%s: could not verify %q", name, msg)
+						return
+					}
+					tampered := append([]byte(nil), b...)
+					tampered[9] ^= 1
+					if _, ok := s.Verify(tampered, time.Minute); ok {
+						t.Errorf("%s: verified a changed message", name)
+						return
+					}
+				}
+			}(g)
+		}
+		wg.Wait()
+	}
+
+	// signatures from concurrent use match those of the reference
+	s := signers["NewTimeSignerFunc"].Signer
+	want := reference.Sign([]byte("message"))
+	var wg sync.WaitGroup
+	for g := 0; g < 16; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 200; i++ {
+				if got := s.Sign([]byte("message")); string(got) != string(want) {
+					t.Errorf("concurrent signature %x differs from %x", got, want)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
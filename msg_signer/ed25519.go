@@ -0,0 +1,71 @@
+package msg_signer
+
+import (
+	"crypto/ed25519"
+	"errors"
+	"fmt"
+)
+
+// ed25519Authenticator signs with a private key and verifies with any of a set of public
+// keys, so verifiers cannot forge messages.
+type ed25519Authenticator struct {
+	priv ed25519.PrivateKey
+	pubs []ed25519.PublicKey
+}
+
+func (a *ed25519Authenticator) sign(data []byte) []byte {
+	if a.priv == nil {
+		return nil
+	}
+	return ed25519.Sign(a.priv, data)
+}
+
+func (a *ed25519Authenticator) verify(data, signature []byte) bool {
+	if len(signature) != ed25519.SignatureSize {
+		return false
+	}
+	for _, pub := range a.pubs {
+		if ed25519.Verify(pub, data, signature) {
+			return true
+		}
+	}
+	return false
+}
+
+// NewEd25519Signer returns a Signer that signs with priv and verifies with any of pubs,
+// or priv's own public key. priv may be nil for a Signer that only verifies; messages it
+// signs have no signature and never verify.
+func NewEd25519Signer(priv ed25519.PrivateKey, pubs []ed25519.PublicKey) (*Signer, error) {
+	a := &ed25519Authenticator{}
+	if priv != nil {
+		if len(priv) != ed25519.PrivateKeySize {
+			return nil, fmt.Errorf("ed25519 private key is %d bytes, not %d", len(priv), ed25519.PrivateKeySize)
+		}
+		a.priv = priv
+		a.pubs = append(a.pubs, priv.Public().(ed25519.PublicKey))
+	}
+	for _, pub := range pubs {
+		if len(pub) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("ed25519 public key is %d bytes, not %d", len(pub), ed25519.PublicKeySize)
+		}
+		a.pubs = append(a.pubs, pub)
+	}
+	if len(a.pubs) == 0 {
+		return nil, errors.New("ed25519 signer needs a private key or public keys")
+	}
+	return &Signer{
+		auth: a,
+	}, nil
+}
+
+// NewEd25519TimeSigner returns a TimeSigner that signs and verifies like
+// NewEd25519Signer.
+func NewEd25519TimeSigner(priv ed25519.PrivateKey, pubs []ed25519.PublicKey) (*TimeSigner, error) {
+	s, err := NewEd25519Signer(priv, pubs)
+	if err != nil {
+		return nil, err
+	}
+	return &TimeSigner{
+		Signer: s,
+	}, nil
+}
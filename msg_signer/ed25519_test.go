@@ -0,0 +1,73 @@
+package msg_signer_test
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"testing"
+	"time"
+
+	"github.com/twitchscience/scoop_protocol/msg_signer"
+)
+
+func newEd25519Key(t *testing.T) (ed25519.PublicKey, ed25519.PrivateKey) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pub, priv
+}
+
+func TestEd25519Signer(t *testing.T) {
+	pub, priv := newEd25519Key(t)
+	otherPub, otherPriv := newEd25519Key(t)
+
+	s, err := msg_signer.NewEd25519TimeSigner(priv, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := s.Sign([]byte("test1test2test3\n\r"))
+	if _, g := s.Verify(b, time.Second*5); !g {
+		t.Error("Signer could not self verify")
+	}
+	if _, g := s.Verify(b, time.Second*0); g {
+		t.Error("Signer failed time duration")
+	}
+
+	// a verifier holding public keys only
+	v, err := msg_signer.NewEd25519TimeSigner(nil, []ed25519.PublicKey{otherPub, pub})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg, g := v.Verify(b, time.Second*5); !g || string(msg) != "test1test2test3\n\r" {
+		t.Error("verifier could not verify")
+	}
+	if _, g := v.Verify(v.Sign([]byte("forged")), time.Second*5); g {
+		t.Error("verifier without a private key signed a message")
+	}
+
+	other, err := msg_signer.NewEd25519TimeSigner(otherPriv, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, g := s.Verify(other.Sign([]byte("test")), time.Second*5); g {
+		t.Error("message signed with an unknown key verified")
+	}
+	tampered := append([]byte(nil), b...)
+	tampered[9] ^= 1
+	if _, g := v.Verify(tampered, time.Second*5); g {
+		t.Error("Signer falsly verified")
+	}
+}
+
+func TestEd25519SignerKeys(t *testing.T) {
+	pub, priv := newEd25519Key(t)
+	if _, err := msg_signer.NewEd25519Signer(nil, nil); err == nil {
+		t.Error("made a signer without keys")
+	}
+	if _, err := msg_signer.NewEd25519Signer(priv[:10], nil); err == nil {
+		t.Error("made a signer with a short private key")
+	}
+	if _, err := msg_signer.NewEd25519Signer(nil, []ed25519.PublicKey{pub[:10]}); err == nil {
+		t.Error("made a signer with a short public key")
+	}
+}
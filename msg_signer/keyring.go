@@ -0,0 +1,129 @@
+package msg_signer
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// MaxKeyIDLength is the longest key ID a Keyring accepts.
+const MaxKeyIDLength = 255
+
+// Keyring holds the HMAC-SHA256 keys of a Signer, so that keys can be rotated without
+// every sender and receiver changing key at once: add the new key everywhere, make it the
+// primary key on the senders, and retire the old key once nothing signs with it. The
+// signature of a message is the ID of the key that made it, prefixed by its length, then
+// the HMAC. It is safe for concurrent use.
+type Keyring struct {
+	mu      sync.RWMutex
+	keys    map[string][]byte
+	primary string
+}
+
+// NewKeyring returns a Keyring holding one key, which is the primary key.
+func NewKeyring(primaryID string, secret []byte) (*Keyring, error) {
+	k := &Keyring{keys: make(map[string][]byte)}
+	if err := k.Add(primaryID, secret); err != nil {
+		return nil, err
+	}
+	k.primary = primaryID
+	return k, nil
+}
+
+// Add adds a key that messages can be verified with. It fails if the ID is taken.
+func (k *Keyring) Add(id string, secret []byte) error {
+	if id == "" || len(id) > MaxKeyIDLength {
+		return fmt.Errorf("key ID must be 1 to %d bytes long", MaxKeyIDLength)
+	}
+	if len(secret) == 0 {
+		return fmt.Errorf("key %s has no secret", id)
+	}
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if _, ok := k.keys[id]; ok {
+		return fmt.Errorf("key %s is already in the keyring", id)
+	}
+	k.keys[id] = append([]byte(nil), secret...)
+	return nil
+}
+
+// SetPrimary makes a key in the keyring the one messages are signed with.
+func (k *Keyring) SetPrimary(id string) error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if _, ok := k.keys[id]; !ok {
+		return fmt.Errorf("key %s is not in the keyring", id)
+	}
+	k.primary = id
+	return nil
+}
+
+// Retire removes a key, so messages signed with it no longer verify. The primary key
+// cannot be retired.
+func (k *Keyring) Retire(id string) error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if _, ok := k.keys[id]; !ok {
+		return fmt.Errorf("key %s is not in the keyring", id)
+	}
+	if id == k.primary {
+		return errors.New("the primary key cannot be retired")
+	}
+	delete(k.keys, id)
+	return nil
+}
+
+// Primary returns the ID of the key messages are signed with.
+func (k *Keyring) Primary() string {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	return k.primary
+}
+
+// IDs returns the sorted IDs of the keys messages can be verified with.
+func (k *Keyring) IDs() []string {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	ids := make([]string, 0, len(k.keys))
+	for id := range k.keys {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+func (k *Keyring) sign(data []byte) []byte {
+	k.mu.RLock()
+	id, secret := k.primary, k.keys[k.primary]
+	k.mu.RUnlock()
+
+	signature := make([]byte, 0, 1+len(id)+sha256.Size)
+	signature = append(signature, byte(len(id)))
+	signature = append(signature, id...)
+	return append(signature, mac(secret, data)...)
+}
+
+func (k *Keyring) verify(data, signature []byte) bool {
+	if len(signature) < 1 || len(signature) < 1+int(signature[0]) {
+		return false
+	}
+	idLen := int(signature[0])
+	id := string(signature[1 : 1+idLen])
+
+	k.mu.RLock()
+	secret, ok := k.keys[id]
+	k.mu.RUnlock()
+	if !ok {
+		return false
+	}
+	return hmac.Equal(signature[1+idLen:], mac(secret, data))
+}
+
+func mac(secret, data []byte) []byte {
+	h := hmac.New(sha256.New, secret)
+	h.Write(data)
+	return h.Sum(nil)
+}
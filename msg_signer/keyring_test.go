@@ -0,0 +1,128 @@
+package msg_signer_test
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/twitchscience/scoop_protocol/msg_signer"
+)
+
+func newKeyring(t *testing.T) *msg_signer.Keyring {
+	k, err := msg_signer.NewKeyring("2016-01", []byte("old secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return k
+}
+
+func TestKeyringRotation(t *testing.T) {
+	// one keyring each for a sender and a receiver, rotated independently
+	sender := newKeyring(t)
+	receiver := newKeyring(t)
+	s := msg_signer.NewKeyringTimeSigner(sender)
+	r := msg_signer.NewKeyringTimeSigner(receiver)
+
+	oldMsg := s.Sign([]byte("signed with the old key"))
+	if msg, g := r.Verify(oldMsg, time.Minute); !g || string(msg) != "signed with the old key" {
+		t.Error("message signed with the old key did not verify")
+	}
+
+	// the receiver learns the new key first, then the sender switches to it
+	if err := receiver.Add("2016-02", []byte("new secret")); err != nil {
+		t.Fatal(err)
+	}
+	if err := sender.Add("2016-02", []byte("new secret")); err != nil {
+		t.Fatal(err)
+	}
+	if err := sender.SetPrimary("2016-02"); err != nil {
+		t.Fatal(err)
+	}
+	newMsg := s.Sign([]byte("signed with the new key"))
+	for _, b := range [][]byte{oldMsg, newMsg} {
+		if _, g := r.Verify(b, time.Minute); !g {
+			t.Errorf("message did not verify before the old key was retired: %q", b)
+		}
+	}
+
+	if err := receiver.SetPrimary("2016-02"); err != nil {
+		t.Fatal(err)
+	}
+	if err := receiver.Retire("2016-01"); err != nil {
+		t.Fatal(err)
+	}
+	if _, g := r.Verify(oldMsg, time.Minute); g {
+		t.Error("message signed with a retired key verified")
+	}
+	if _, g := r.Verify(newMsg, time.Minute); !g {
+		t.Error("message signed with the new key did not verify")
+	}
+}
+
+func TestKeyringRejectsForgeries(t *testing.T) {
+	k := newKeyring(t)
+	if err := k.Add("2016-99", []byte("other secret")); err != nil {
+		t.Fatal(err)
+	}
+	s := msg_signer.NewKeyringSigner(k)
+	b := s.Sign([]byte("test1test2test3\n\r"))
+	if _, g := s.Verify(b); !g {
+		t.Fatal("Signer could not self verify")
+	}
+
+	// claiming another key in the keyring
+	forged := append([]byte(nil), b...)
+	copy(forged[len(forged)-sha256.Size-len("2016-01"):], "2016-99")
+	if _, g := s.Verify(forged); g {
+		t.Error("signature verified under another key ID")
+	}
+
+	// a plain HMAC of the message, without a key ID
+	plain := msg_signer.NewSigner(hmac.New(sha256.New, []byte("old secret"))).Sign([]byte("test1test2test3\n\r"))
+	if _, g := s.Verify(plain); g {
+		t.Error("signature without a key ID verified")
+	}
+
+	for i := 0; i < len(b); i++ {
+		if _, g := s.Verify(b[:i]); g {
+			t.Errorf("signature truncated to %d bytes verified", i)
+		}
+	}
+}
+
+func TestKeyringManagement(t *testing.T) {
+	k := newKeyring(t)
+	if err := k.Add("2016-01", []byte("again")); err == nil {
+		t.Error("added a key ID twice")
+	}
+	if err := k.Add("", []byte("secret")); err == nil {
+		t.Error("added an empty key ID")
+	}
+	if err := k.Add(string(make([]byte, msg_signer.MaxKeyIDLength+1)), []byte("secret")); err == nil {
+		t.Error("added an overlong key ID")
+	}
+	if err := k.Add("empty", nil); err == nil {
+		t.Error("added a key without a secret")
+	}
+	if err := k.Retire("2016-01"); err == nil {
+		t.Error("retired the primary key")
+	}
+	if err := k.Retire("missing"); err == nil {
+		t.Error("retired a missing key")
+	}
+	if err := k.SetPrimary("missing"); err == nil {
+		t.Error("made a missing key primary")
+	}
+	if _, err := msg_signer.NewKeyring("", []byte("secret")); err == nil {
+		t.Error("made a keyring with an empty key ID")
+	}
+
+	if err := k.Add("2016-02", []byte("new secret")); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(k.IDs(), []string{"2016-01", "2016-02"}) || k.Primary() != "2016-01" {
+		t.Errorf("unexpected keyring %v, primary %s", k.IDs(), k.Primary())
+	}
+}
@@ -2,34 +2,98 @@ package msg_signer
 
 import (
 	"crypto/hmac"
+	"crypto/rand"
 	"encoding/binary"
 	"hash"
 	"io"
 	"io/ioutil"
+	"sync"
 	"time"
 )
 
+// authenticator makes and checks the signature that Signer appends to the length
+// prefixed message.
+type authenticator interface {
+	sign(data []byte) []byte
+	verify(data, signature []byte) bool
+}
+
+// hashAuthenticator signs with a single keyed hash, such as an HMAC. The hash can only
+// be used by one goroutine at a time, so signing is serialized.
+type hashAuthenticator struct {
+	mu sync.Mutex
+	h  hash.Hash
+}
+
+func (a *hashAuthenticator) sign(data []byte) []byte {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.h.Reset()
+	a.h.Write(data)
+	return a.h.Sum(nil)
+}
+
+func (a *hashAuthenticator) verify(data, signature []byte) bool {
+	return hmac.Equal(signature, a.sign(data))
+}
+
+// poolAuthenticator signs with keyed hashes made by a constructor, reusing them
+// through a pool so that goroutines can sign at the same time.
+type poolAuthenticator struct {
+	pool sync.Pool
+}
+
+func (a *poolAuthenticator) sign(data []byte) []byte {
+	h := a.pool.Get().(hash.Hash)
+	defer a.pool.Put(h)
+	h.Reset()
+	h.Write(data)
+	return h.Sum(nil)
+}
+
+func (a *poolAuthenticator) verify(data, signature []byte) bool {
+	return hmac.Equal(signature, a.sign(data))
+}
+
+// Signer appends a signature to messages and checks it. It is safe for concurrent use.
 type Signer struct {
-	h hash.Hash
+	auth authenticator
 }
 
+// NewSigner returns a Signer that signs with h. As h can only be used by one goroutine
+// at a time, prefer NewSignerFunc for signers shared between goroutines.
 func NewSigner(h hash.Hash) *Signer {
 	return &Signer{
-		h: h,
+		auth: &hashAuthenticator{h: h},
+	}
+}
+
+// NewSignerFunc returns a Signer that signs with keyed hashes made by newHash, such as
+//
+//	func() hash.Hash { return hmac.New(sha256.New, secret) }
+//
+// Each goroutine signing at the same time gets its own hash.
+func NewSignerFunc(newHash func() hash.Hash) *Signer {
+	a := &poolAuthenticator{}
+	a.pool.New = func() interface{} { return newHash() }
+	return &Signer{
+		auth: a,
 	}
 }
 
-func (s *Signer) signature(msg []byte) []byte {
-	s.h.Reset()
-	s.h.Write(msg)
-	return s.h.Sum(nil)
+// NewKeyringSigner returns a Signer that signs with the keyring's primary key and
+// verifies with any of its keys.
+func NewKeyringSigner(k *Keyring) *Signer {
+	return &Signer{
+		auth: k,
+	}
 }
 
 func (s *Signer) Sign(msg []byte) []byte {
 	b := make([]byte, 8+len(msg))
 	binary.PutVarint(b, int64(len(msg)))
 	b = append(b[:8], msg...)
-	b = append(b, s.signature(b)...)
+	b = append(b, s.auth.sign(b)...)
 	return b
 }
 
@@ -50,8 +114,7 @@ func (s *Signer) Verify(b []byte) ([]byte, bool) {
 
 	msg := b[8:length]
 	signature := b[length:]
-	signature2 := s.signature(b[:length])
-	return msg, hmac.Equal(signature, signature2)
+	return msg, s.auth.verify(b[:length], signature)
 }
 
 type TimeSigner struct {
@@ -64,24 +127,77 @@ func NewTimeSigner(h hash.Hash) *TimeSigner {
 	}
 }
 
+// NewTimeSignerFunc returns a TimeSigner that signs with keyed hashes made by newHash,
+// like NewSignerFunc.
+func NewTimeSignerFunc(newHash func() hash.Hash) *TimeSigner {
+	return &TimeSigner{
+		Signer: NewSignerFunc(newHash),
+	}
+}
+
+// NewKeyringTimeSigner returns a TimeSigner that signs with the keyring's primary key
+// and verifies with any of its keys.
+func NewKeyringTimeSigner(k *Keyring) *TimeSigner {
+	return &TimeSigner{
+		Signer: NewKeyringSigner(k),
+	}
+}
+
+// NonceSize is the length of the nonces added by TimeSigner.SignWithNonce.
+const NonceSize = 16
+
+// TimeSigner prefixes messages with an 8-byte header holding the time they were signed
+// as a varint. The time never needs the header's last byte, which holds flags. The only
+// flag, flagNonce, means that a NonceSize nonce follows the header.
+const (
+	timeHeaderSize = 8
+	flagNonce      = 1
+)
+
 func (s *TimeSigner) Sign(msg []byte) []byte {
-	b := make([]byte, 8+len(msg))
+	b := make([]byte, timeHeaderSize+len(msg))
 	binary.PutVarint(b, time.Now().Unix())
-	b = append(b[:8], msg...)
+	b = append(b[:timeHeaderSize], msg...)
 	return s.Signer.Sign(b)
 }
 
+// SignWithNonce signs msg along with a random nonce, which receivers can remember to
+// refuse the message if it is sent again. Receivers that predate nonces cannot read it.
+func (s *TimeSigner) SignWithNonce(msg []byte) ([]byte, error) {
+	b := make([]byte, timeHeaderSize+NonceSize, timeHeaderSize+NonceSize+len(msg))
+	binary.PutVarint(b, time.Now().Unix())
+	b[timeHeaderSize-1] = flagNonce
+	if _, err := rand.Read(b[timeHeaderSize:]); err != nil {
+		return nil, err
+	}
+	b = append(b, msg...)
+	return s.Signer.Sign(b), nil
+}
+
 func (s *TimeSigner) Verify(b []byte, dur time.Duration) ([]byte, bool) {
-	msg, ok := s.Signer.Verify(b)
-	if !ok {
-		return nil, ok
+	msg, _, ok := s.VerifyWithNonce(b, dur)
+	return msg, ok
+}
+
+// VerifyWithNonce verifies a message like Verify, and also returns its nonce, or nil if
+// it was signed without one.
+func (s *TimeSigner) VerifyWithNonce(b []byte, dur time.Duration) (msg []byte, nonce []byte, ok bool) {
+	msg, ok = s.Signer.Verify(b)
+	if !ok || len(msg) < timeHeaderSize {
+		return nil, nil, false
 	}
 
-	unixTime, _ := binary.Varint(msg[:8])
+	unixTime, _ := binary.Varint(msg[:timeHeaderSize])
 	if time.Since(time.Unix(unixTime, 0)) > dur {
-		return nil, false
+		return nil, nil, false
+	}
+	if msg[timeHeaderSize-1]&flagNonce == 0 {
+		return msg[timeHeaderSize:], nil, true
+	}
+	if len(msg) < timeHeaderSize+NonceSize {
+		return nil, nil, false
 	}
-	return msg[8:], true
+	return msg[timeHeaderSize+NonceSize:], msg[timeHeaderSize : timeHeaderSize+NonceSize], true
 }
 
 func (s *TimeSigner) PagedVerify(r io.Reader, dur time.Duration) ([]byte, bool) {
@@ -91,3 +207,12 @@ func (s *TimeSigner) PagedVerify(r io.Reader, dur time.Duration) ([]byte, bool)
 	}
 	return s.Verify(b, dur)
 }
+
+// PagedVerifyWithNonce is VerifyWithNonce for a message read from r.
+func (s *TimeSigner) PagedVerifyWithNonce(r io.Reader, dur time.Duration) ([]byte, []byte, bool) {
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, nil, false
+	}
+	return s.VerifyWithNonce(b, dur)
+}
@@ -0,0 +1,86 @@
+package msg_signer
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// NonceStore remembers the nonces of messages that have been verified, so that a
+// message can be refused if it is replayed.
+type NonceStore interface {
+	// Seen records a nonce, which need not be remembered after expires, and returns
+	// true if it was already recorded.
+	Seen(nonce []byte, expires time.Time) bool
+}
+
+// DefaultNonceStoreSize is the number of nonces a MemoryNonceStore remembers when
+// NewMemoryNonceStore is given a size of 0 or less.
+const DefaultNonceStoreSize = 100000
+
+// MemoryNonceStore is a NonceStore that holds nonces in memory until they expire. If it
+// fills up it forgets the oldest nonces first, which lets their messages be replayed, so
+// it should be sized for the number of messages received within the expiry window. It
+// is safe for concurrent use.
+type MemoryNonceStore struct {
+	size int
+
+	mu      sync.Mutex
+	order   *list.List // of *nonceEntry, oldest at the back
+	entries map[string]*list.Element
+}
+
+type nonceEntry struct {
+	nonce   string
+	expires time.Time
+}
+
+// NewMemoryNonceStore returns a MemoryNonceStore holding at most size nonces.
+func NewMemoryNonceStore(size int) *MemoryNonceStore {
+	if size <= 0 {
+		size = DefaultNonceStoreSize
+	}
+	return &MemoryNonceStore{
+		size:    size,
+		order:   list.New(),
+		entries: make(map[string]*list.Element),
+	}
+}
+
+func (m *MemoryNonceStore) Seen(nonce []byte, expires time.Time) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	key := string(nonce)
+	if el, ok := m.entries[key]; ok {
+		if el.Value.(*nonceEntry).expires.After(now) {
+			return true
+		}
+		m.remove(el)
+	}
+	if !expires.After(now) {
+		return false
+	}
+
+	m.entries[key] = m.order.PushFront(&nonceEntry{nonce: key, expires: expires})
+	for m.order.Len() > m.size {
+		m.remove(m.order.Back())
+	}
+	for el := m.order.Back(); el != nil && !el.Value.(*nonceEntry).expires.After(now); el = m.order.Back() {
+		m.remove(el)
+	}
+	return false
+}
+
+// Len returns the number of nonces being remembered.
+func (m *MemoryNonceStore) Len() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.order.Len()
+}
+
+func (m *MemoryNonceStore) remove(el *list.Element) {
+	m.order.Remove(el)
+	delete(m.entries, el.Value.(*nonceEntry).nonce)
+}
@@ -0,0 +1,84 @@
+package msg_signer_test
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"testing"
+	"time"
+
+	"github.com/twitchscience/scoop_protocol/msg_signer"
+)
+
+func TestSignWithNonce(t *testing.T) {
+	s := msg_signer.NewTimeSigner(hmac.New(sha256.New, []byte("secret")))
+	b1, err := s.SignWithNonce([]byte("test1test2test3\n\r"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b2, err := s.SignWithNonce([]byte("test1test2test3\n\r"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msg, nonce1, g := s.VerifyWithNonce(b1, time.Second*5)
+	if !g || string(msg) != "test1test2test3\n\r" || len(nonce1) != msg_signer.NonceSize {
+		t.Errorf("Signer could not self verify: %q %x %v", msg, nonce1, g)
+	}
+	_, nonce2, _ := s.VerifyWithNonce(b2, time.Second*5)
+	if bytes.Equal(nonce1, nonce2) {
+		t.Error("two messages have the same nonce")
+	}
+
+	// Verify takes messages with or without nonces
+	if msg, g := s.Verify(b1, time.Second*5); !g || string(msg) != "test1test2test3\n\r" {
+		t.Errorf("Verify could not read a message with a nonce: %q", msg)
+	}
+	if _, nonce, g := s.VerifyWithNonce(s.Sign([]byte("test4")), time.Second*5); !g || nonce != nil {
+		t.Errorf("message without a nonce has nonce %x", nonce)
+	}
+	if _, _, g := s.VerifyWithNonce(b1, time.Second*0); g {
+		t.Error("Signer failed time duration")
+	}
+	if _, _, g := s.PagedVerifyWithNonce(bytes.NewReader(b1), time.Second*5); !g {
+		t.Error("Signer could not self verify from a reader")
+	}
+
+	tampered := append([]byte(nil), b1...)
+	tampered[8+8] ^= 1
+	if _, _, g := s.VerifyWithNonce(tampered, time.Second*5); g {
+		t.Error("Signer verified a changed nonce")
+	}
+}
+
+func TestMemoryNonceStore(t *testing.T) {
+	m := msg_signer.NewMemoryNonceStore(2)
+	later := time.Now().Add(time.Hour)
+	if m.Seen([]byte("a"), later) {
+		t.Error("new nonce was seen")
+	}
+	if !m.Seen([]byte("a"), later) {
+		t.Error("nonce was not remembered")
+	}
+
+	// expired nonces are forgotten
+	if m.Seen([]byte("b"), time.Now().Add(-time.Second)) || m.Seen([]byte("b"), time.Now().Add(-time.Second)) {
+		t.Error("expired nonce was remembered")
+	}
+	if m.Len() != 1 {
+		t.Errorf("expected 1 nonce, got %d", m.Len())
+	}
+
+	// the oldest nonces go first when the store is full
+	m.Seen([]byte("c"), later)
+	m.Seen([]byte("d"), later)
+	if m.Len() != 2 {
+		t.Errorf("expected 2 nonces, got %d", m.Len())
+	}
+	if m.Seen([]byte("a"), later) {
+		t.Error("oldest nonce was not forgotten")
+	}
+	if !m.Seen([]byte("d"), later) {
+		t.Error("newest nonce was forgotten")
+	}
+}
@@ -0,0 +1,121 @@
+// Package redshift renders scoop_protocol schemas as Redshift DDL.
+package redshift
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/twitchscience/scoop_protocol/scoop_protocol"
+	"github.com/twitchscience/scoop_protocol/transformer"
+)
+
+// CreateTable returns the CREATE TABLE statement for the event described by cfg.
+func CreateTable(cfg *scoop_protocol.Config) (string, error) {
+	var b strings.Builder
+	fmt.Fprintf(&b, "CREATE TABLE %s (\n", QuoteIdentifier(cfg.EventName))
+	for i, col := range cfg.Columns {
+		def, err := createColumnDefinition(col)
+		if err != nil {
+			return "", err
+		}
+		b.WriteString("    ")
+		b.WriteString(def)
+		if i < len(cfg.Columns)-1 {
+			b.WriteString(",")
+		}
+		b.WriteString("\n")
+	}
+	b.WriteString(");")
+	return b.String(), nil
+}
+
+// AlterTable returns the statements that apply ops to the table, in the order that
+// scoop_protocol.ApplyOperations would apply them. Operations about dropping the event
+// only record its state and produce no statements. Added columns cannot be a dist or sort
+// key, and are never NOT NULL, since the rows already in the table hold NULL.
+func AlterTable(table string, ops []scoop_protocol.Operation) ([]string, error) {
+	var stmts []string
+	for _, op := range scoop_protocol.SortOperations(ops) {
+		switch op.Action {
+		case scoop_protocol.ADD:
+			def, err := addColumnDefinition(op.AddedColumn())
+			if err != nil {
+				return nil, err
+			}
+			stmts = append(stmts, fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s;", QuoteIdentifier(table), def))
+		case scoop_protocol.DELETE:
+			stmts = append(stmts, fmt.Sprintf("ALTER TABLE %s DROP COLUMN %s;",
+				QuoteIdentifier(table), QuoteIdentifier(op.Name)))
+		case scoop_protocol.RENAME:
+			stmts = append(stmts, fmt.Sprintf("ALTER TABLE %s RENAME COLUMN %s TO %s;",
+				QuoteIdentifier(table), QuoteIdentifier(op.Name),
+				QuoteIdentifier(op.NewOutboundName())))
+		case scoop_protocol.REQUEST_DROP_EVENT, scoop_protocol.CANCEL_DROP_EVENT, scoop_protocol.DROP_EVENT:
+		default:
+			return nil, fmt.Errorf("unknown action %q on column %s", op.Action, op.Name)
+		}
+	}
+	return stmts, nil
+}
+
+// QuoteIdentifier returns name quoted for use as a Redshift table or column name.
+func QuoteIdentifier(name string) string {
+	return `"` + strings.Replace(name, `"`, `""`, -1) + `"`
+}
+
+// createColumnDefinition returns the name, type and attributes of col as used in
+// CREATE TABLE.
+func createColumnDefinition(col scoop_protocol.ColumnDefinition) (string, error) {
+	parts, opts, err := columnParts(col)
+	if err != nil {
+		return "", err
+	}
+	if opts.DistKey {
+		parts = append(parts, "DISTKEY")
+	}
+	if opts.SortKey {
+		parts = append(parts, "SORTKEY")
+	}
+	if opts.NotNull {
+		parts = append(parts, "NOT NULL")
+	}
+	return strings.Join(parts, " "), nil
+}
+
+// addColumnDefinition returns the name, type and attributes of col as used in ALTER
+// TABLE ... ADD COLUMN. Redshift cannot add a dist or sort key, nor a NOT NULL column
+// without a default, so NOT NULL is left out.
+func addColumnDefinition(col scoop_protocol.ColumnDefinition) (string, error) {
+	parts, opts, err := columnParts(col)
+	if err != nil {
+		return "", err
+	}
+	if opts.DistKey || opts.SortKey {
+		return "", fmt.Errorf("column %s: an added column cannot be a dist or sort key", col.OutboundName)
+	}
+	return strings.Join(parts, " "), nil
+}
+
+// columnParts returns the name, type and encoding of col, and its parsed options.
+func columnParts(col scoop_protocol.ColumnDefinition) ([]string, transformer.ColumnOptions, error) {
+	t, ok := transformer.Lookup(col.Transformer)
+	if !ok {
+		return nil, transformer.ColumnOptions{}, fmt.Errorf("column %s: unknown transformer %q", col.OutboundName, col.Transformer)
+	}
+	opts, err := transformer.ParseColumnOptions(col.ColumnCreationOptions)
+	if err != nil {
+		return nil, opts, fmt.Errorf("column %s: %v", col.OutboundName, err)
+	}
+
+	parts := []string{QuoteIdentifier(col.OutboundName), t.SQLType}
+	if opts.Length != 0 {
+		if !t.Requires(transformer.OptionLength) {
+			return nil, opts, fmt.Errorf("column %s: %s column cannot have a length", col.OutboundName, col.Transformer)
+		}
+		parts[1] = fmt.Sprintf("%s(%d)", t.SQLType, opts.Length)
+	}
+	if opts.Encoding != "" {
+		parts = append(parts, "ENCODE "+strings.ToUpper(opts.Encoding))
+	}
+	return parts, opts, nil
+}
@@ -0,0 +1,103 @@
+package redshift
+
+import (
+	"flag"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/twitchscience/scoop_protocol/scoop_protocol"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+var testConfig = scoop_protocol.Config{
+	EventName: "minute-watched",
+	Columns: []scoop_protocol.ColumnDefinition{
+		{InboundName: "time", OutboundName: "time", Transformer: "f@timestamp@unix", ColumnCreationOptions: " sortkey"},
+		{InboundName: "time", OutboundName: "time_utc", Transformer: "f@timestamp@unix-utc"},
+		{InboundName: "ip", OutboundName: "city", Transformer: "ipCity"},
+		{InboundName: "ip", OutboundName: "country", Transformer: "ipCountry"},
+		{InboundName: "ip", OutboundName: "region", Transformer: "ipRegion"},
+		{InboundName: "ip", OutboundName: "asn", Transformer: "ipAsn"},
+		{InboundName: "ip", OutboundName: "asn_id", Transformer: "ipAsnInteger"},
+		{InboundName: "login", OutboundName: "login", Transformer: "varchar", ColumnCreationOptions: "(32) encode zstd"},
+		{InboundName: "user_id", OutboundName: "user_id", Transformer: "userIDWithMapping",
+			ColumnCreationOptions: "distkey", SupportingColumns: "login"},
+		{InboundName: "minutes", OutboundName: "minutes", Transformer: "int"},
+		{InboundName: "bytes", OutboundName: "bytes", Transformer: "bigint", ColumnCreationOptions: "not null"},
+		{InboundName: "ratio", OutboundName: "ratio", Transformer: "float"},
+		{InboundName: "live", OutboundName: "live", Transformer: "bool"},
+		{InboundName: "quote", OutboundName: `say "hi"`, Transformer: "varchar", ColumnCreationOptions: "(255)"},
+	},
+	Version: 2,
+}
+
+// checkGolden compares actual to the contents of testdata/name, rewriting the file
+// instead when the test is run with -update.
+func checkGolden(t *testing.T, name, actual string) {
+	path := filepath.Join("testdata", name)
+	if *update {
+		require.NoError(t, ioutil.WriteFile(path, []byte(actual), 0644))
+	}
+	expected, err := ioutil.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, string(expected), actual)
+}
+
+func TestCreateTable(t *testing.T) {
+	ddl, err := CreateTable(&testConfig)
+	require.NoError(t, err)
+	checkGolden(t, "create_table.golden", ddl+"\n")
+}
+
+func TestAlterTable(t *testing.T) {
+	ops := []scoop_protocol.Operation{
+		scoop_protocol.NewAddOperation("game", "game", "varchar", "(64)", ""),
+		scoop_protocol.NewRenameOperation("login", "user_login"),
+		scoop_protocol.NewDeleteOperation("ratio"),
+		scoop_protocol.NewAddOperation("channel_id", "channel_id", "userIDWithMapping", "", "channel"),
+		scoop_protocol.NewAddOperation("bytes", "bytes", "bigint", "not null encode zstd", ""),
+		scoop_protocol.NewRequestDropEventOperation("unused"),
+	}
+	for i := range ops {
+		ops[i].Ordering = len(ops) - i
+	}
+
+	stmts, err := AlterTable(testConfig.EventName, ops)
+	require.NoError(t, err)
+	checkGolden(t, "alter_table.golden", strings.Join(stmts, "\n")+"\n")
+
+	// dropping the event is recorded, not done
+	stmts, err = AlterTable(testConfig.EventName, []scoop_protocol.Operation{
+		scoop_protocol.NewRequestDropEventOperation("unused"),
+		scoop_protocol.NewDropEventOperation("unused"),
+		scoop_protocol.NewCancelDropEventOperation("used after all"),
+	})
+	require.NoError(t, err)
+	assert.Empty(t, stmts)
+
+	for _, options := range []string{"distkey", "sortkey", "(32) sortkey"} {
+		_, err = AlterTable(testConfig.EventName, []scoop_protocol.Operation{
+			scoop_protocol.NewAddOperation("game", "game", "varchar", "(64) "+options, ""),
+		})
+		assert.Error(t, err, options)
+	}
+}
+
+func TestInvalidColumns(t *testing.T) {
+	for _, col := range []scoop_protocol.ColumnDefinition{
+		{InboundName: "a", OutboundName: "a", Transformer: "string"},
+		{InboundName: "a", OutboundName: "a", Transformer: "bigint", ColumnCreationOptions: "(32)"},
+		{InboundName: "a", OutboundName: "a", Transformer: "varchar", ColumnCreationOptions: "(32"},
+	} {
+		_, err := CreateTable(&scoop_protocol.Config{EventName: "e", Columns: []scoop_protocol.ColumnDefinition{col}})
+		assert.Error(t, err, "%v", col)
+	}
+
+	_, err := AlterTable("e", []scoop_protocol.Operation{{Action: "truncate"}})
+	assert.Error(t, err)
+}
@@ -0,0 +1,83 @@
+package row
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TimestampFormat is how timestamps are written for Redshift. The wall clock of the
+// time's location is written, since Redshift TIMESTAMP columns have no time zone.
+const TimestampFormat = "2006-01-02 15:04:05.999999"
+
+// NullTSV is how WriteTSV writes NULL, matching the COPY default.
+const NullTSV = `\N`
+
+// tsvEscaper escapes for COPY ... ESCAPE, which loads a backslash followed by any character
+// as that character. Control characters are therefore escaped as themselves: "\t" would
+// load as the letter t.
+var tsvEscaper = strings.NewReplacer(`\`, `\\`, "\t", "\\\t", "\n", "\\\n", "\r", "\\\r")
+
+// WriteTSV writes the row as one line of tab separated values, suitable for
+// COPY ... DELIMITER '\t' ESCAPE.
+func (r *Row) WriteTSV(w io.Writer) error {
+	fields := make([]string, len(r.Values))
+	for i, v := range r.Values {
+		if v == nil {
+			fields[i] = NullTSV
+			continue
+		}
+		s, err := formatValue(v)
+		if err != nil {
+			return err
+		}
+		fields[i] = tsvEscaper.Replace(s)
+	}
+	_, err := io.WriteString(w, strings.Join(fields, "\t")+"\n")
+	return err
+}
+
+// WriteCSV writes the row as one CSV record, suitable for COPY ... CSV. NULL is written
+// as an empty field.
+func (r *Row) WriteCSV(w io.Writer) error {
+	fields := make([]string, len(r.Values))
+	for i, v := range r.Values {
+		if v == nil {
+			continue
+		}
+		s, err := formatValue(v)
+		if err != nil {
+			return err
+		}
+		fields[i] = s
+	}
+	cw := csv.NewWriter(w)
+	if err := cw.Write(fields); err != nil {
+		return err
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// formatValue returns the text Redshift loads as v.
+func formatValue(v interface{}) (string, error) {
+	switch v := v.(type) {
+	case string:
+		return v, nil
+	case int32:
+		return strconv.FormatInt(int64(v), 10), nil
+	case int64:
+		return strconv.FormatInt(v, 10), nil
+	case float64:
+		return strconv.FormatFloat(v, 'g', -1, 64), nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case time.Time:
+		return v.Format(TimestampFormat), nil
+	default:
+		return "", fmt.Errorf("cannot format %T for Redshift", v)
+	}
+}
@@ -0,0 +1,72 @@
+package row
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var formatRow = &Row{Values: []interface{}{
+	time.Date(2014, 4, 17, 20, 59, 40, 123000000, time.UTC),
+	"tab\there, \"quoted\", back\\slash\nnewline",
+	nil,
+	int32(-2),
+	int64(1234),
+	true,
+	0.5,
+	1e21,
+}}
+
+func TestWriteTSV(t *testing.T) {
+	var b bytes.Buffer
+	require.NoError(t, formatRow.WriteTSV(&b))
+	assert.Equal(t, "2014-04-17 20:59:40.123\ttab\\\there, \"quoted\", back\\\\slash\\\nnewline\t\\N\t-2\t1234\ttrue\t0.5\t1e+21\n", b.String())
+}
+
+// copyUnescape splits a line written by WriteTSV into fields the way Redshift's
+// COPY ... DELIMITER '\t' ESCAPE does.
+func copyUnescape(line string) []string {
+	var fields []string
+	var field []byte
+	for i := 0; i < len(line); i++ {
+		switch c := line[i]; {
+		case c == '\\' && i+1 < len(line):
+			i++
+			field = append(field, line[i])
+		case c == '\t':
+			fields = append(fields, string(field))
+			field = nil
+		case c == '\n' && i == len(line)-1:
+		default:
+			field = append(field, c)
+		}
+	}
+	return append(fields, string(field))
+}
+
+func TestWriteTSVRoundTrip(t *testing.T) {
+	values := []string{"tab\there", "new\nline\r\n", `back\slash\`, `\t\n`, `\N`}
+	r := &Row{Values: make([]interface{}, len(values))}
+	for i, v := range values {
+		r.Values[i] = v
+	}
+	var b bytes.Buffer
+	require.NoError(t, r.WriteTSV(&b))
+	assert.Equal(t, values, copyUnescape(b.String()))
+}
+
+func TestWriteCSV(t *testing.T) {
+	var b bytes.Buffer
+	require.NoError(t, formatRow.WriteCSV(&b))
+	assert.Equal(t, "2014-04-17 20:59:40.123,\"tab\there, \"\"quoted\"\", back\\slash\nnewline\",,-2,1234,true,0.5,1e+21\n", b.String())
+}
+
+func TestFormatUnknownType(t *testing.T) {
+	r := &Row{Values: []interface{}{errors.New("oops")}}
+	assert.Error(t, r.WriteTSV(&bytes.Buffer{}))
+	assert.Error(t, r.WriteCSV(&bytes.Buffer{}))
+}
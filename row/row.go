@@ -0,0 +1,169 @@
+// Package row turns spade events into rows of the tables described by scoop_protocol
+// configs, so that every loader converts events the same way.
+package row
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"strconv"
+
+	"github.com/twitchscience/scoop_protocol/scoop_protocol"
+	"github.com/twitchscience/scoop_protocol/spade"
+	"github.com/twitchscience/scoop_protocol/transformer"
+)
+
+// ColumnError is a property that could not be converted for a column.
+type ColumnError struct {
+	Column string // OutboundName of the column
+	Err    error
+}
+
+func (e *ColumnError) Error() string {
+	return fmt.Sprintf("column %s: %v", e.Column, e.Err)
+}
+
+// Row is one row of an event's table.
+type Row struct {
+	// Values holds the value of each column in Config order, nil for NULL.
+	Values []interface{}
+	// Errors lists the columns that could not be converted. Their values are NULL.
+	Errors []*ColumnError
+	// Sources records, for userIDWithMapping columns, which property produced the value.
+	Sources map[string]string
+}
+
+type column struct {
+	scoop_protocol.ColumnDefinition
+	transformer transformer.Transformer
+	convert     transformer.ConvertFunc
+}
+
+// RowBuilder builds rows for one event's Config.
+type RowBuilder struct {
+	eventName string
+	columns   []column
+	mapper    *transformer.UserIDMapper
+	proxies   spade.TrustedProxies
+}
+
+// NewRowBuilder returns a RowBuilder for cfg that converts columns with the transformers
+// in registry. mapper resolves userIDWithMapping columns and may only be nil if cfg
+// has none.
+func NewRowBuilder(cfg *scoop_protocol.Config, registry *transformer.Registry, mapper *transformer.UserIDMapper) (*RowBuilder, error) {
+	b := &RowBuilder{eventName: cfg.EventName, mapper: mapper}
+	for _, def := range cfg.Columns {
+		t, ok := registry.Lookup(def.Transformer)
+		if !ok {
+			return nil, fmt.Errorf("column %s: unknown transformer %q", def.OutboundName, def.Transformer)
+		}
+		convert, err := t.ConverterFor(def.ColumnCreationOptions)
+		if err != nil {
+			return nil, fmt.Errorf("column %s: %v", def.OutboundName, err)
+		}
+		if t.Mapping && mapper == nil {
+			return nil, fmt.Errorf("column %s: no mapper for %s", def.OutboundName, t.Name)
+		}
+		b.columns = append(b.columns, column{ColumnDefinition: def, transformer: t, convert: convert})
+	}
+	return b, nil
+}
+
+// UseTrustedProxies makes the builder look up ip transformers from the client address
+// found through X-Forwarded-For with proxies, rather than from ev.ClientIp. It must be
+// called before any rows are built.
+func (b *RowBuilder) UseTrustedProxies(proxies spade.TrustedProxies) {
+	b.proxies = proxies
+}
+
+// Columns returns the names of the row's columns, in order.
+func (b *RowBuilder) Columns() []string {
+	names := make([]string, len(b.columns))
+	for i, c := range b.columns {
+		names[i] = c.OutboundName
+	}
+	return names
+}
+
+// BuildEvent decodes the payloads in ev.Data and builds a row for each one that is an
+// instance of the builder's event. It fails only if Data cannot be decoded; conversion
+// problems are reported in each Row.
+func (b *RowBuilder) BuildEvent(ev *spade.Event) ([]*Row, error) {
+	payloads, err := ev.DecodePayload()
+	if err != nil {
+		return nil, err
+	}
+	var rows []*Row
+	for _, p := range payloads {
+		if p.Event == b.eventName {
+			rows = append(rows, b.Build(ev, p.Properties))
+		}
+	}
+	return rows, nil
+}
+
+// Build builds the row for one payload of ev with the given properties. Columns with ip
+// transformers are converted from ev.EffectiveClientIP with the builder's trusted proxies,
+// and those with user agent transformers from ev.UserAgent, rather than from a property.
+func (b *RowBuilder) Build(ev *spade.Event, properties map[string]interface{}) *Row {
+	values := make(map[string]string, len(properties))
+	for k, v := range properties {
+		values[k] = propertyString(v)
+	}
+
+	var clientIP net.IP
+	row := &Row{Values: make([]interface{}, len(b.columns))}
+	for i, c := range b.columns {
+		var v interface{}
+		var err error
+		switch {
+		case c.transformer.IPLookup:
+			if clientIP == nil {
+				clientIP = ev.EffectiveClientIP(b.proxies)
+			}
+			v, err = c.transformer.ConvertIP(clientIP)
+		case c.transformer.UserAgent:
+			v, err = c.convert(ev.UserAgent)
+		case c.transformer.Mapping:
+			var r transformer.Resolution
+			r, err = b.mapper.Resolve(c.InboundName, c.SupportingColumns, values)
+			if r.Column != "" {
+				if row.Sources == nil {
+					row.Sources = make(map[string]string)
+				}
+				row.Sources[c.OutboundName] = r.Column
+			}
+			v = r.Value
+		default:
+			v, err = c.convert(values[c.InboundName])
+		}
+		if err != nil {
+			row.Errors = append(row.Errors, &ColumnError{Column: c.OutboundName, Err: err})
+			v = nil
+		}
+		row.Values[i] = v
+	}
+	return row
+}
+
+// propertyString returns the text form of a decoded JSON property.
+func propertyString(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case json.Number:
+		return v.String()
+	case bool:
+		return strconv.FormatBool(v)
+	case float64:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	default:
+		b, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Sprint(v)
+		}
+		return string(b)
+	}
+}
@@ -0,0 +1,172 @@
+package row
+
+import (
+	"encoding/base64"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/twitchscience/scoop_protocol/scoop_protocol"
+	"github.com/twitchscience/scoop_protocol/spade"
+	"github.com/twitchscience/scoop_protocol/transformer"
+	"github.com/twitchscience/scoop_protocol/useragent"
+)
+
+var testConfig = scoop_protocol.Config{
+	EventName: "minute-watched",
+	Columns: []scoop_protocol.ColumnDefinition{
+		{InboundName: "time", OutboundName: "time", Transformer: "f@timestamp@unix-utc"},
+		{InboundName: "ip", OutboundName: "country", Transformer: "ipCountry"},
+		{InboundName: "login", OutboundName: "login", Transformer: "varchar", ColumnCreationOptions: "(4)"},
+		{InboundName: "user_id", OutboundName: "user_id", Transformer: "userIDWithMapping", SupportingColumns: "login"},
+		{InboundName: "minutes", OutboundName: "minutes", Transformer: "int"},
+		{InboundName: "live", OutboundName: "live", Transformer: "bool"},
+		{InboundName: "ratio", OutboundName: "ratio", Transformer: "float"},
+	},
+}
+
+type countryLookup map[string]string
+
+func (c countryLookup) Lookup(ip net.IP) (*transformer.GeoRecord, error) {
+	if ip.Equal(net.IPv4(10, 0, 0, 1)) {
+		return nil, errors.New("lookup failed")
+	}
+	if country, ok := c[ip.String()]; ok {
+		return &transformer.GeoRecord{Country: country}, nil
+	}
+	return nil, nil
+}
+
+func newTestBuilder(t *testing.T) *RowBuilder {
+	registry := transformer.NewRegistry()
+	registry.UseGeoLookup(countryLookup{"81.2.69.160": "GB"})
+	mapper := transformer.NewUserIDMapper(transformer.MemoryMappingSource{"login": {"snoop": 1234}})
+	b, err := NewRowBuilder(&testConfig, registry, mapper)
+	require.NoError(t, err)
+	return b
+}
+
+func newTestEvent(ip net.IP, data string) *spade.Event {
+	return spade.NewEvent(time.Unix(1397768380, 0), ip, "", "uuid", base64.StdEncoding.EncodeToString([]byte(data)), "", spade.EXTERNAL_EDGE)
+}
+
+func TestBuildEvent(t *testing.T) {
+	b := newTestBuilder(t)
+	assert.Equal(t, []string{"time", "country", "login", "user_id", "minutes", "live", "ratio"}, b.Columns())
+
+	ev := newTestEvent(net.IPv4(81, 2, 69, 160), `[
+		{"event": "minute-watched", "properties": {"time": 1397768380, "login": "snoopdogg", "minutes": 2, "live": true, "ratio": 0.5}},
+		{"event": "pageview", "properties": {"time": 1397768380}},
+		{"event": "minute-watched", "properties": {"time": "1397768381.5", "login": "snoop", "minutes": "many", "user_id": null}}
+	]`)
+	rows, err := b.BuildEvent(ev)
+	require.NoError(t, err)
+	require.Len(t, rows, 2)
+
+	assert.Equal(t, []interface{}{
+		time.Date(2014, 4, 17, 20, 59, 40, 0, time.UTC), "GB", "snoo", nil, int32(2), true, 0.5,
+	}, rows[0].Values)
+	assert.Empty(t, rows[0].Errors)
+	assert.Empty(t, rows[0].Sources)
+
+	assert.Equal(t, []interface{}{
+		time.Date(2014, 4, 17, 20, 59, 41, 500000000, time.UTC), "GB", "snoo", int64(1234), nil, nil, nil,
+	}, rows[1].Values)
+	require.Len(t, rows[1].Errors, 1)
+	assert.Equal(t, "minutes", rows[1].Errors[0].Column)
+	assert.Equal(t, map[string]string{"user_id": "login"}, rows[1].Sources)
+}
+
+func TestBuildSingleObject(t *testing.T) {
+	b := newTestBuilder(t)
+	ev := newTestEvent(net.IPv4(10, 0, 0, 1), `{"event": "minute-watched", "properties": {"user_id": 42}}`)
+	rows, err := b.BuildEvent(ev)
+	require.NoError(t, err)
+	require.Len(t, rows, 1)
+	assert.Equal(t, []interface{}{nil, nil, nil, int64(42), nil, nil, nil}, rows[0].Values)
+	require.Len(t, rows[0].Errors, 1, "geo lookup failure was not reported")
+	assert.Equal(t, "country", rows[0].Errors[0].Column)
+	assert.Equal(t, map[string]string{"user_id": "user_id"}, rows[0].Sources)
+}
+
+func TestBuildBehindProxy(t *testing.T) {
+	b := newTestBuilder(t)
+	ev := newTestEvent(net.IPv4(10, 0, 0, 2), `{"event": "minute-watched", "properties": {}}`)
+	ev.XForwardedFor = "1.1.1.1, 81.2.69.160"
+	rows, err := b.BuildEvent(ev)
+	require.NoError(t, err)
+	require.Len(t, rows, 1)
+	assert.Nil(t, rows[0].Values[1], "X-Forwarded-For was believed without trusted proxies")
+
+	b.UseTrustedProxies(spade.DefaultTrustedProxies())
+	rows, err = b.BuildEvent(ev)
+	require.NoError(t, err)
+	require.Len(t, rows, 1)
+	assert.Equal(t, "GB", rows[0].Values[1], "country was not looked up from the forwarded address")
+}
+
+func TestBuildRedacted(t *testing.T) {
+	b := newTestBuilder(t)
+	proxies := spade.DefaultTrustedProxies()
+	b.UseTrustedProxies(proxies)
+	ev := newTestEvent(net.IPv4(10, 0, 0, 2), `{"event": "minute-watched", "properties": {}}`)
+	ev.XForwardedFor = "81.2.69.160"
+	require.NoError(t, (&spade.RedactionPolicy{TruncateIP: true, TrustedProxies: proxies}).RedactEvent(ev))
+
+	rows, err := b.BuildEvent(ev)
+	require.NoError(t, err)
+	require.Len(t, rows, 1)
+	assert.Nil(t, rows[0].Values[1], "country was looked up from the unredacted address")
+}
+
+func TestBuildUserAgent(t *testing.T) {
+	registry := transformer.NewRegistry()
+	require.NoError(t, useragent.Register(registry))
+	cfg := scoop_protocol.Config{EventName: "e", Columns: []scoop_protocol.ColumnDefinition{
+		{InboundName: "user_agent", OutboundName: "browser", Transformer: "uaBrowser"},
+		{InboundName: "user_agent", OutboundName: "bot", Transformer: "uaBot"},
+	}}
+	b, err := NewRowBuilder(&cfg, registry, nil)
+	require.NoError(t, err)
+
+	ev := newTestEvent(nil, `{"event": "e", "properties": {"user_agent": "Firefox"}}`)
+	ev.UserAgent = "curl/8.4.0"
+	rows, err := b.BuildEvent(ev)
+	require.NoError(t, err)
+	require.Len(t, rows, 1)
+	assert.Equal(t, []interface{}{"curl", true}, rows[0].Values)
+	assert.Empty(t, rows[0].Errors)
+}
+
+func TestBuildEventErrors(t *testing.T) {
+	b := newTestBuilder(t)
+	for _, data := range []string{"not base64!", base64.StdEncoding.EncodeToString([]byte("{not json"))} {
+		ev := newTestEvent(nil, "")
+		ev.Data = data
+		_, err := b.BuildEvent(ev)
+		assert.Error(t, err, data)
+	}
+}
+
+func TestNewRowBuilderErrors(t *testing.T) {
+	registry := transformer.NewRegistry()
+	for _, col := range []scoop_protocol.ColumnDefinition{
+		{InboundName: "a", OutboundName: "a", Transformer: "string"},
+		{InboundName: "a", OutboundName: "a", Transformer: "varchar"},
+		{InboundName: "a", OutboundName: "a", Transformer: "userIDWithMapping", SupportingColumns: "b"},
+	} {
+		cfg := scoop_protocol.Config{EventName: "e", Columns: []scoop_protocol.ColumnDefinition{col}}
+		_, err := NewRowBuilder(&cfg, registry, nil)
+		assert.Error(t, err, "%v", col)
+	}
+
+	// ip transformers without a GeoLookup have nothing to convert with
+	cfg := scoop_protocol.Config{EventName: "e", Columns: []scoop_protocol.ColumnDefinition{
+		{InboundName: "ip", OutboundName: "city", Transformer: "ipCity"},
+	}}
+	_, err := NewRowBuilder(&cfg, registry, nil)
+	assert.Error(t, err)
+}
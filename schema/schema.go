@@ -13,3 +13,8 @@ func (u *UpdateSchemaRequest) ConvertToRedshiftUpdate() *scoop_protocol.Config {
 		Columns:   u.Columns,
 	}
 }
+
+// Validate returns an error describing every problem with the requested schema, or nil.
+func (u *UpdateSchemaRequest) Validate() error {
+	return u.ConvertToRedshiftUpdate().Validate()
+}
@@ -0,0 +1,80 @@
+package scoop_protocol
+
+// DiffConfigs returns the operations that turn old into new, such that
+// ApplyOperations(*old, DiffConfigs(old, new)) has the columns of new.
+//
+// A column that keeps its OutboundName and definition is left alone. A column that
+// disappears from old while a column with the same InboundName, Transformer,
+// ColumnCreationOptions and SupportingColumns appears in new is treated as a rename.
+// Since ADD always appends, the untouched and renamed columns must form a prefix of new
+// in their original relative order; columns that are moved out of place are deleted and
+// re-added at their new position. The operations are ordered deletes, renames, adds and
+// are stamped with the version after old.Version.
+func DiffConfigs(old, new *Config) []Operation {
+	oldNames := make(map[string]int, len(old.Columns))
+	for i, c := range old.Columns {
+		oldNames[c.OutboundName] = i
+	}
+	newNames := make(map[string]bool, len(new.Columns))
+	for _, c := range new.Columns {
+		newNames[c.OutboundName] = true
+	}
+
+	// match[j] is the index of the column in old that becomes new.Columns[j], or -1.
+	match := make([]int, len(new.Columns))
+	paired := make(map[int]bool)
+	for j, c := range new.Columns {
+		match[j] = -1
+		if i, ok := oldNames[c.OutboundName]; ok {
+			if old.Columns[i] == c {
+				match[j] = i
+				paired[i] = true
+			}
+			continue
+		}
+		for i, o := range old.Columns {
+			if !paired[i] && !newNames[o.OutboundName] && isRenameOf(o, c) {
+				match[j] = i
+				paired[i] = true
+				break
+			}
+		}
+	}
+
+	kept := make(map[int]bool)
+	prefix := 0
+	last := -1
+	for prefix < len(new.Columns) && match[prefix] > last {
+		last = match[prefix]
+		kept[last] = true
+		prefix++
+	}
+
+	var ops []Operation
+	for i, c := range old.Columns {
+		if !kept[i] {
+			ops = append(ops, NewDeleteOperation(c.OutboundName))
+		}
+	}
+	for j, c := range new.Columns[:prefix] {
+		if from := old.Columns[match[j]].OutboundName; from != c.OutboundName {
+			ops = append(ops, NewRenameOperation(from, c.OutboundName))
+		}
+	}
+	for _, c := range new.Columns[prefix:] {
+		ops = append(ops, NewAddOperation(c.OutboundName, c.InboundName, c.Transformer,
+			c.ColumnCreationOptions, c.SupportingColumns))
+	}
+
+	for i := range ops {
+		ops[i].Version = old.Version + 1
+		ops[i].Ordering = i
+	}
+	return ops
+}
+
+// isRenameOf returns true if new differs from old only in its OutboundName.
+func isRenameOf(old, new ColumnDefinition) bool {
+	old.OutboundName = new.OutboundName
+	return old == new
+}
@@ -0,0 +1,129 @@
+package scoop_protocol
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiffConfigs(t *testing.T) {
+	testCases := []struct {
+		name    string
+		columns []ColumnDefinition
+		ops     []Operation
+	}{
+		{
+			name:    "unchanged",
+			columns: baseOperationsConfig.Columns,
+			ops:     nil,
+		},
+		{
+			name: "add",
+			columns: append(baseOperationsConfig.Columns[:3:3],
+				ColumnDefinition{"game", "game", "varchar", "(64)", ""}),
+			ops: []Operation{
+				NewAddOperation("game", "game", "varchar", "(64)", ""),
+			},
+		},
+		{
+			name: "delete",
+			columns: []ColumnDefinition{
+				{"time", "time", "f@timestamp@unix", "", ""},
+				{"channel", "channel", "varchar", "(32)", ""},
+			},
+			ops: []Operation{
+				NewDeleteOperation("login"),
+			},
+		},
+		{
+			name: "rename",
+			columns: []ColumnDefinition{
+				{"time", "time", "f@timestamp@unix", "", ""},
+				{"login", "user_login", "varchar", "(32)", ""},
+				{"channel", "channel", "varchar", "(32)", ""},
+			},
+			ops: []Operation{
+				NewRenameOperation("login", "user_login"),
+			},
+		},
+		{
+			name: "changed definition",
+			columns: []ColumnDefinition{
+				{"time", "time", "f@timestamp@unix", "", ""},
+				{"login", "login", "varchar", "(32)", ""},
+				{"channel", "channel", "varchar", "(64)", ""},
+			},
+			ops: []Operation{
+				NewDeleteOperation("channel"),
+				NewAddOperation("channel", "channel", "varchar", "(64)", ""),
+			},
+		},
+		{
+			name: "changed transformer is not a rename",
+			columns: []ColumnDefinition{
+				{"time", "time", "f@timestamp@unix", "", ""},
+				{"login", "login", "varchar", "(32)", ""},
+				{"channel", "channel_id", "bigint", "", ""},
+			},
+			ops: []Operation{
+				NewDeleteOperation("channel"),
+				NewAddOperation("channel_id", "channel", "bigint", "", ""),
+			},
+		},
+		{
+			name: "reorder",
+			columns: []ColumnDefinition{
+				{"time", "time", "f@timestamp@unix", "", ""},
+				{"channel", "channel", "varchar", "(32)", ""},
+				{"login", "login", "varchar", "(32)", ""},
+			},
+			ops: []Operation{
+				NewDeleteOperation("login"),
+				NewAddOperation("login", "login", "varchar", "(32)", ""),
+			},
+		},
+		{
+			name: "mixed",
+			columns: []ColumnDefinition{
+				{"time", "time", "f@timestamp@unix", "", ""},
+				{"channel", "channel_name", "varchar", "(32)", ""},
+				{"user_id", "user_id", "userIDWithMapping", "", "login"},
+			},
+			ops: []Operation{
+				NewDeleteOperation("login"),
+				NewRenameOperation("channel", "channel_name"),
+				NewAddOperation("user_id", "user_id", "userIDWithMapping", "", "login"),
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		newConfig := Config{EventName: baseOperationsConfig.EventName, Columns: tc.columns, Version: 7}
+		ops := DiffConfigs(&baseOperationsConfig, &newConfig)
+
+		for i := range tc.ops {
+			tc.ops[i].Version = baseOperationsConfig.Version + 1
+			tc.ops[i].Ordering = i
+		}
+		assert.Equal(t, tc.ops, ops, tc.name)
+
+		applied, err := ApplyOperations(baseOperationsConfig, ops)
+		require.NoError(t, err, tc.name)
+		assert.Equal(t, tc.columns, applied.Columns, tc.name)
+	}
+}
+
+func TestDiffConfigsSwap(t *testing.T) {
+	newConfig := Config{
+		EventName: baseOperationsConfig.EventName,
+		Columns: []ColumnDefinition{
+			{"time", "time", "f@timestamp@unix", "", ""},
+			{"login", "channel", "varchar", "(32)", ""},
+			{"channel", "login", "varchar", "(32)", ""},
+		},
+	}
+	applied, err := ApplyOperations(baseOperationsConfig, DiffConfigs(&baseOperationsConfig, &newConfig))
+	require.NoError(t, err)
+	assert.Equal(t, newConfig.Columns, applied.Columns)
+}
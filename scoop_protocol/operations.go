@@ -0,0 +1,170 @@
+package scoop_protocol
+
+import (
+	"fmt"
+	"sort"
+)
+
+// DuplicateColumnError is returned when an ADD operation names a column that already exists.
+type DuplicateColumnError struct {
+	Operation Operation
+}
+
+func (e *DuplicateColumnError) Error() string {
+	return fmt.Sprintf("cannot add column %s: column already exists", e.Operation.Name)
+}
+
+// MissingColumnError is returned when a DELETE or RENAME operation names a column that
+// does not exist.
+type MissingColumnError struct {
+	Operation Operation
+}
+
+func (e *MissingColumnError) Error() string {
+	return fmt.Sprintf("cannot %s column %s: column does not exist", e.Operation.Action, e.Operation.Name)
+}
+
+// RenameCollisionError is returned when a RENAME operation targets a name that is
+// already used by another column.
+type RenameCollisionError struct {
+	Operation Operation
+}
+
+func (e *RenameCollisionError) Error() string {
+	return fmt.Sprintf("cannot rename column %s to %s: column already exists",
+		e.Operation.Name, e.Operation.NewOutboundName())
+}
+
+// EmptyRenameError is returned when a RENAME operation has no new name.
+type EmptyRenameError struct {
+	Operation Operation
+}
+
+func (e *EmptyRenameError) Error() string {
+	return fmt.Sprintf("cannot rename column %s: new name is empty", e.Operation.Name)
+}
+
+// StaleOperationError is returned when an operation carries a version that the Config
+// has already reached.
+type StaleOperationError struct {
+	Operation     Operation
+	ConfigVersion int
+}
+
+func (e *StaleOperationError) Error() string {
+	return fmt.Sprintf("operation %s on %s has version %d but config is already at version %d",
+		e.Operation.Action, e.Operation.Name, e.Operation.Version, e.ConfigVersion)
+}
+
+// SortOperations returns a copy of ops ordered by Version and then Ordering, which is the
+// order in which they must be applied. Operations with equal keys keep their relative order.
+func SortOperations(ops []Operation) []Operation {
+	sorted := make([]Operation, len(ops))
+	copy(sorted, ops)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].Version != sorted[j].Version {
+			return sorted[i].Version < sorted[j].Version
+		}
+		return sorted[i].Ordering < sorted[j].Ordering
+	})
+	return sorted
+}
+
+// ApplyOperations returns the Config that results from applying ops to cfg. cfg is not modified.
+//
+// Operations are applied in Version/Ordering order. An operation with a zero Version has not
+// been assigned one yet and is treated as belonging to the version after cfg.Version; any
+// other operation must be newer than cfg.Version. The returned Config has the highest version
+// that was applied. Event level actions (REQUEST_DROP_EVENT, DROP_EVENT, CANCEL_DROP_EVENT)
+// do not change the columns but still advance the version.
+func ApplyOperations(cfg Config, ops []Operation) (Config, error) {
+	next := cfg.Version + 1
+	versioned := make([]Operation, len(ops))
+	for i, op := range ops {
+		if op.Version == 0 {
+			op.Version = next
+		} else if op.Version <= cfg.Version {
+			return Config{}, &StaleOperationError{Operation: ops[i], ConfigVersion: cfg.Version}
+		}
+		versioned[i] = op
+	}
+
+	columns := make([]ColumnDefinition, len(cfg.Columns))
+	copy(columns, cfg.Columns)
+	version := cfg.Version
+
+	for _, op := range SortOperations(versioned) {
+		var err error
+		columns, err = applyOperation(columns, op)
+		if err != nil {
+			return Config{}, err
+		}
+		version = op.Version
+	}
+
+	return Config{
+		EventName: cfg.EventName,
+		Columns:   columns,
+		Version:   version,
+	}, nil
+}
+
+// AddedColumn returns the column created by an ADD operation.
+func (op *Operation) AddedColumn() ColumnDefinition {
+	return ColumnDefinition{
+		InboundName:           op.ActionMetadata[metadataInbound],
+		OutboundName:          op.Name,
+		Transformer:           op.ActionMetadata[metadataColumnType],
+		ColumnCreationOptions: op.ActionMetadata[metadataColumnOptions],
+		SupportingColumns:     op.ActionMetadata[metadataSupportingColumns],
+	}
+}
+
+// NewOutboundName returns the name that a RENAME operation gives its column.
+func (op *Operation) NewOutboundName() string {
+	return op.ActionMetadata[metadataNewOutbound]
+}
+
+func applyOperation(columns []ColumnDefinition, op Operation) ([]ColumnDefinition, error) {
+	switch op.Action {
+	case ADD:
+		if findColumn(columns, op.Name) != -1 {
+			return nil, &DuplicateColumnError{Operation: op}
+		}
+		return append(columns, op.AddedColumn()), nil
+	case DELETE:
+		i := findColumn(columns, op.Name)
+		if i == -1 {
+			return nil, &MissingColumnError{Operation: op}
+		}
+		return append(columns[:i], columns[i+1:]...), nil
+	case RENAME:
+		i := findColumn(columns, op.Name)
+		if i == -1 {
+			return nil, &MissingColumnError{Operation: op}
+		}
+		newName := op.NewOutboundName()
+		if newName == "" {
+			return nil, &EmptyRenameError{Operation: op}
+		}
+		if findColumn(columns, newName) != -1 {
+			return nil, &RenameCollisionError{Operation: op}
+		}
+		columns[i].OutboundName = newName
+		return columns, nil
+	case REQUEST_DROP_EVENT, DROP_EVENT, CANCEL_DROP_EVENT:
+		return columns, nil
+	default:
+		return nil, fmt.Errorf("unknown action %q on column %s", op.Action, op.Name)
+	}
+}
+
+// findColumn returns the index of the column with the given outbound name, or -1.
+func findColumn(columns []ColumnDefinition, outbound string) int {
+	for i, c := range columns {
+		if c.OutboundName == outbound {
+			return i
+		}
+	}
+	return -1
+}
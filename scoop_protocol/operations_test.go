@@ -0,0 +1,105 @@
+package scoop_protocol
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var baseOperationsConfig = Config{
+	EventName: "minute-watched",
+	Columns: []ColumnDefinition{
+		{"time", "time", "f@timestamp@unix", "", ""},
+		{"login", "login", "varchar", "(32)", ""},
+		{"channel", "channel", "varchar", "(32)", ""},
+	},
+	Version: 3,
+}
+
+func TestApplyOperations(t *testing.T) {
+	ops := []Operation{
+		NewRenameOperation("channel", "channel_name"),
+		NewDeleteOperation("login"),
+		NewAddOperation("user_id", "user_id", "userIDWithMapping", "", "login"),
+	}
+	for i := range ops {
+		ops[i].Ordering = i
+	}
+
+	cfg, err := ApplyOperations(baseOperationsConfig, ops)
+	require.NoError(t, err)
+	assert.Equal(t, Config{
+		EventName: "minute-watched",
+		Columns: []ColumnDefinition{
+			{"time", "time", "f@timestamp@unix", "", ""},
+			{"channel", "channel_name", "varchar", "(32)", ""},
+			{"user_id", "user_id", "userIDWithMapping", "", "login"},
+		},
+		Version: 4,
+	}, cfg)
+
+	// the input must not be modified
+	assert.Equal(t, "login", baseOperationsConfig.Columns[1].OutboundName)
+	assert.Equal(t, "channel", baseOperationsConfig.Columns[2].OutboundName)
+	assert.Equal(t, 3, baseOperationsConfig.Version)
+}
+
+func TestApplyOperationsOrdering(t *testing.T) {
+	rename := NewRenameOperation("channel", "channel_name")
+	rename.Version, rename.Ordering = 5, 0
+	add := NewAddOperation("channel", "channel", "varchar", "(64)", "")
+	add.Version, add.Ordering = 5, 1
+	del := NewDeleteOperation("channel_name")
+	del.Version, del.Ordering = 6, 0
+
+	// supplied out of order; applying them as given would fail on the add
+	cfg, err := ApplyOperations(baseOperationsConfig, []Operation{del, add, rename})
+	require.NoError(t, err)
+	assert.Equal(t, 6, cfg.Version)
+	assert.Equal(t, []ColumnDefinition{
+		{"time", "time", "f@timestamp@unix", "", ""},
+		{"login", "login", "varchar", "(32)", ""},
+		{"channel", "channel", "varchar", "(64)", ""},
+	}, cfg.Columns)
+}
+
+func TestApplyOperationsErrors(t *testing.T) {
+	stale := NewDeleteOperation("login")
+	stale.Version = 3
+
+	testCases := []struct {
+		name string
+		op   Operation
+		err  interface{}
+	}{
+		{"duplicate add", NewAddOperation("login", "login", "varchar", "(32)", ""), &DuplicateColumnError{}},
+		{"missing delete", NewDeleteOperation("game"), &MissingColumnError{}},
+		{"missing rename", NewRenameOperation("game", "game_name"), &MissingColumnError{}},
+		{"rename collision", NewRenameOperation("login", "channel"), &RenameCollisionError{}},
+		{"empty rename", NewRenameOperation("login", ""), &EmptyRenameError{}},
+		{"rename without new name", Operation{Action: RENAME, Name: "login"}, &EmptyRenameError{}},
+		{"stale version", stale, &StaleOperationError{}},
+	}
+	for _, tc := range testCases {
+		_, err := ApplyOperations(baseOperationsConfig, []Operation{tc.op})
+		require.Error(t, err, tc.name)
+		assert.IsType(t, tc.err, err, tc.name)
+	}
+
+	_, err := ApplyOperations(baseOperationsConfig, []Operation{{Action: "truncate", Name: "login"}})
+	assert.Error(t, err, "unknown action worked")
+}
+
+func TestApplyOperationsDropEvent(t *testing.T) {
+	cfg, err := ApplyOperations(baseOperationsConfig, []Operation{NewRequestDropEventOperation("unused")})
+	require.NoError(t, err)
+	assert.Equal(t, baseOperationsConfig.Columns, cfg.Columns)
+	assert.Equal(t, 4, cfg.Version)
+}
+
+func TestApplyNoOperations(t *testing.T) {
+	cfg, err := ApplyOperations(baseOperationsConfig, nil)
+	require.NoError(t, err)
+	assert.Equal(t, baseOperationsConfig, cfg)
+}
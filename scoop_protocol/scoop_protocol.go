@@ -1,6 +1,7 @@
 package scoop_protocol
 
 import (
+	"crypto/ed25519"
 	"encoding/json"
 	"errors"
 	"io"
@@ -65,15 +66,24 @@ type EventMetadataConfig struct {
 	Metadata map[string](map[string]EventMetadataRow)
 }
 
+// Keys used in Operation.ActionMetadata.
+const (
+	metadataInbound           = "inbound"
+	metadataColumnType        = "column_type"
+	metadataColumnOptions     = "column_options"
+	metadataSupportingColumns = "supporting_columns"
+	metadataNewOutbound       = "new_outbound"
+)
+
 func NewAddOperation(outbound, inbound, type_, options, columns string) Operation {
 	return Operation{
 		Action: ADD,
 		Name:   outbound,
 		ActionMetadata: map[string]string{
-			"inbound":            inbound,
-			"column_type":        type_,
-			"column_options":     options,
-			"supporting_columns": columns,
+			metadataInbound:           inbound,
+			metadataColumnType:        type_,
+			metadataColumnOptions:     options,
+			metadataSupportingColumns: columns,
 		},
 	}
 }
@@ -91,7 +101,7 @@ func NewRenameOperation(current, new string) Operation {
 		Action: RENAME,
 		Name:   current,
 		ActionMetadata: map[string]string{
-			"new_outbound": new,
+			metadataNewOutbound: new,
 		},
 	}
 }
@@ -166,13 +176,46 @@ type FakeScoopSigner struct{}
 type AuthScoopSigner struct {
 	TimeSigner *msg_signer.TimeSigner
 	Exp        time.Duration
+	// Nonces, if set, makes the signer sign messages with a nonce and refuse messages
+	// whose nonce it has seen before, so each message verifies only once.
+	Nonces msg_signer.NonceStore
+	// RequireNonce refuses messages signed without a nonce. Leave it off until every
+	// sender has Nonces set.
+	RequireNonce bool
+}
+
+// Ed25519ScoopSigner signs with an Ed25519 private key and verifies with a set of
+// public keys, in the same envelope as AuthScoopSigner. Unlike HMAC secrets, the public
+// keys given to services that only need to verify do not let them forge messages.
+type Ed25519ScoopSigner struct {
+	AuthScoopSigner
+	canSign bool
+}
+
+// NewEd25519ScoopSigner returns an Ed25519ScoopSigner that signs with priv and accepts
+// messages up to exp old signed by any of pubs or by priv. priv may be nil for a signer
+// that only verifies.
+func NewEd25519ScoopSigner(priv ed25519.PrivateKey, pubs []ed25519.PublicKey, exp time.Duration) (*Ed25519ScoopSigner, error) {
+	ts, err := msg_signer.NewEd25519TimeSigner(priv, pubs)
+	if err != nil {
+		return nil, err
+	}
+	return &Ed25519ScoopSigner{
+		AuthScoopSigner: AuthScoopSigner{TimeSigner: ts, Exp: exp},
+		canSign:         priv != nil,
+	}, nil
 }
 
 var (
-	BadVerified error = errors.New("Bad Signature")
+	BadVerified     error = errors.New("Bad Signature")
+	NoPrivateKey    error = errors.New("No Private Key")
+	MissingNonce    error = errors.New("Missing Nonce")
+	ReplayedMessage error = errors.New("Replayed Message")
 )
 
-// For now we are turning off the signer
+// GetScoopSigner returns a FakeScoopSigner, which neither signs nor verifies.
+//
+// Deprecated: use NewScoopSigner with the config returned by ScoopSignerConfigFromEnv.
 func GetScoopSigner() ScoopSigner {
 	return &FakeScoopSigner{}
 }
@@ -186,17 +229,39 @@ func (s *AuthScoopSigner) SignJsonBody(o interface{}) ([]byte, error) {
 }
 
 func (s *AuthScoopSigner) SignBody(b []byte) ([]byte, error) {
+	if s.Nonces != nil {
+		return s.TimeSigner.SignWithNonce(b)
+	}
 	return s.TimeSigner.Sign(b), nil
 }
 
-func (s *AuthScoopSigner) GetConfig(body io.Reader) (*Config, error) {
-	msg, verified := s.TimeSigner.PagedVerify(body, s.Exp)
+// verify returns the message signed in body, checking its nonce against Nonces.
+func (s *AuthScoopSigner) verify(body io.Reader) ([]byte, error) {
+	msg, nonce, verified := s.TimeSigner.PagedVerifyWithNonce(body, s.Exp)
 	if !verified {
 		return nil, BadVerified
 	}
+	if nonce == nil {
+		if s.RequireNonce {
+			return nil, MissingNonce
+		}
+		return msg, nil
+	}
+	// The message was signed at most Exp ago, so its nonce can be forgotten after Exp.
+	if s.Nonces != nil && s.Nonces.Seen(nonce, time.Now().Add(s.Exp)) {
+		return nil, ReplayedMessage
+	}
+	return msg, nil
+}
+
+func (s *AuthScoopSigner) GetConfig(body io.Reader) (*Config, error) {
+	msg, err := s.verify(body)
+	if err != nil {
+		return nil, err
+	}
 
 	c := new(Config)
-	err := json.Unmarshal(msg, c)
+	err = json.Unmarshal(msg, c)
 	if err != nil {
 		return nil, err
 	}
@@ -205,13 +270,13 @@ func (s *AuthScoopSigner) GetConfig(body io.Reader) (*Config, error) {
 }
 
 func (s *AuthScoopSigner) GetRowCopyRequest(body io.Reader) (*RowCopyRequest, error) {
-	msg, verified := s.TimeSigner.PagedVerify(body, s.Exp)
-	if !verified {
-		return nil, BadVerified
+	msg, err := s.verify(body)
+	if err != nil {
+		return nil, err
 	}
 
 	c := new(RowCopyRequest)
-	err := json.Unmarshal(msg, c)
+	err = json.Unmarshal(msg, c)
 	if err != nil {
 		return nil, err
 	}
@@ -219,6 +284,21 @@ func (s *AuthScoopSigner) GetRowCopyRequest(body io.Reader) (*RowCopyRequest, er
 	return c, nil
 }
 
+func (s *Ed25519ScoopSigner) SignJsonBody(o interface{}) ([]byte, error) {
+	req, err := json.Marshal(o)
+	if err != nil {
+		return nil, err
+	}
+	return s.SignBody(req)
+}
+
+func (s *Ed25519ScoopSigner) SignBody(b []byte) ([]byte, error) {
+	if !s.canSign {
+		return nil, NoPrivateKey
+	}
+	return s.AuthScoopSigner.SignBody(b)
+}
+
 func (s *FakeScoopSigner) SignJsonBody(o interface{}) ([]byte, error) {
 	req, err := json.Marshal(o)
 	if err != nil {
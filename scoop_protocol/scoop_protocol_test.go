@@ -2,8 +2,15 @@ package scoop_protocol
 
 import (
 	"bytes"
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
 	"fmt"
 	"testing"
+	"time"
+
+	"github.com/twitchscience/scoop_protocol/msg_signer"
 )
 
 func TestConfig(t *testing.T) {
@@ -82,3 +89,107 @@ func TestRowCopyRequest(t *testing.T) {
 		t.Fail()
 	}
 }
+
+func newAuthSigner(nonces msg_signer.NonceStore) *AuthScoopSigner {
+	return &AuthScoopSigner{
+		TimeSigner: msg_signer.NewTimeSigner(hmac.New(sha256.New, []byte("secret"))),
+		Exp:        time.Minute,
+		Nonces:     nonces,
+	}
+}
+
+func TestAuthSignerReplay(t *testing.T) {
+	s := newAuthSigner(msg_signer.NewMemoryNonceStore(0))
+	b, err := s.SignJsonBody(RowCopyRequest{"key", "table", 0})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.GetRowCopyRequest(bytes.NewReader(b)); err != nil {
+		t.Errorf("first delivery failed: %v", err)
+	}
+	if _, err := s.GetRowCopyRequest(bytes.NewReader(b)); err != ReplayedMessage {
+		t.Errorf("expected ReplayedMessage, got %v", err)
+	}
+
+	b, err = s.SignJsonBody(Config{EventName: "test"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.GetConfig(bytes.NewReader(b)); err != nil {
+		t.Errorf("first delivery failed: %v", err)
+	}
+	if _, err := s.GetConfig(bytes.NewReader(b)); err != ReplayedMessage {
+		t.Errorf("expected ReplayedMessage, got %v", err)
+	}
+}
+
+func TestAuthSignerNonceRollout(t *testing.T) {
+	// a sender without nonces, and a receiver that checks them
+	old := newAuthSigner(nil)
+	receiver := newAuthSigner(msg_signer.NewMemoryNonceStore(0))
+	b, err := old.SignJsonBody(RowCopyRequest{"key", "table", 0})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := receiver.GetRowCopyRequest(bytes.NewReader(b)); err != nil {
+		t.Errorf("message without a nonce was refused: %v", err)
+	}
+	receiver.RequireNonce = true
+	if _, err := receiver.GetRowCopyRequest(bytes.NewReader(b)); err != MissingNonce {
+		t.Errorf("expected MissingNonce, got %v", err)
+	}
+
+	// signed with a nonce but received without a store
+	b, err = receiver.SignJsonBody(RowCopyRequest{"key", "table", 0})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		if _, err := old.GetRowCopyRequest(bytes.NewReader(b)); err != nil {
+			t.Errorf("message with a nonce was refused: %v", err)
+		}
+	}
+	b[len(b)-1] ^= 1
+	if _, err := old.GetRowCopyRequest(bytes.NewReader(b)); err != BadVerified {
+		t.Errorf("expected BadVerified, got %v", err)
+	}
+}
+
+func TestEd25519ScoopSigner(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var signer, verifier ScoopSigner
+	signer, err = NewEd25519ScoopSigner(priv, nil, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	verifier, err = NewEd25519ScoopSigner(nil, []ed25519.PublicKey{pub}, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testConfig := Config{EventName: "test", Columns: []ColumnDefinition{{"", "test1", "int", "", ""}}, Version: 2}
+	b, err := signer.SignJsonBody(testConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := verifier.GetConfig(bytes.NewReader(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprintf("%v", *c) != fmt.Sprintf("%v", testConfig) {
+		t.Errorf("Expected %v got %v", testConfig, c)
+	}
+
+	if _, err := verifier.SignJsonBody(testConfig); err != NoPrivateKey {
+		t.Errorf("expected NoPrivateKey, got %v", err)
+	}
+
+	// HMAC signed messages are not accepted
+	hmacSigned, _ := newAuthSigner(nil).SignJsonBody(RowCopyRequest{"key", "table", 0})
+	if _, err := verifier.GetRowCopyRequest(bytes.NewReader(hmacSigned)); err != BadVerified {
+		t.Errorf("expected BadVerified, got %v", err)
+	}
+}
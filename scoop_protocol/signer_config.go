@@ -0,0 +1,330 @@
+package scoop_protocol
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"hash"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/twitchscience/scoop_protocol/msg_signer"
+)
+
+// The modes of ScoopSignerConfig.
+const (
+	SignerModeFake    = "fake"
+	SignerModeHMAC    = "hmac"
+	SignerModeEd25519 = "ed25519"
+)
+
+// MinHMACKeyLength is the shortest HMAC secret NewScoopSigner accepts.
+const MinHMACKeyLength = 32
+
+// ScoopSignerConfig describes the ScoopSigner made by NewScoopSigner.
+type ScoopSignerConfig struct {
+	// Mode is SignerModeFake, SignerModeHMAC or SignerModeEd25519.
+	Mode string
+	// HMACKeyFile holds the shared secret in hmac mode. Surrounding whitespace is
+	// ignored.
+	HMACKeyFile string
+	// HMACKeyFiles maps key IDs to files holding their secrets, for hmac mode with a
+	// msg_signer.Keyring so that keys can be rotated. It replaces HMACKeyFile, and the
+	// two sign differently, so every sender and receiver must use the same one.
+	HMACKeyFiles map[string]string
+	// HMACPrimaryKeyID is the key in HMACKeyFiles that messages are signed with.
+	HMACPrimaryKeyID string
+	// Ed25519PrivateKeyFile holds the PEM encoded PKCS #8 private key to sign with in
+	// ed25519 mode. Services that only verify leave it empty.
+	Ed25519PrivateKeyFile string
+	// Ed25519PublicKeyFiles hold the PEM encoded PKIX public keys whose messages are
+	// accepted in ed25519 mode, besides the private key's own.
+	Ed25519PublicKeyFiles []string
+	// Expiry is how old a message may be and still be accepted.
+	Expiry time.Duration
+	// Nonces makes the signer sign with nonces and refuse replayed messages.
+	Nonces bool
+	// RequireNonce makes the signer refuse messages signed without a nonce, which could
+	// otherwise be replayed until they expire. It needs Nonces, and should be set once
+	// every sender has Nonces set.
+	RequireNonce bool
+	// AllowFake must be set for fake mode, which neither signs nor verifies.
+	AllowFake bool
+}
+
+// The environment variables read by ScoopSignerConfigFromEnv.
+const (
+	EnvSignerMode                  = "SCOOP_SIGNER_MODE"
+	EnvSignerHMACKeyFile           = "SCOOP_SIGNER_HMAC_KEY_FILE"
+	EnvSignerHMACKeyFiles          = "SCOOP_SIGNER_HMAC_KEY_FILES" // comma separated id=path pairs
+	EnvSignerHMACPrimaryKeyID      = "SCOOP_SIGNER_HMAC_PRIMARY_KEY_ID"
+	EnvSignerEd25519PrivateKeyFile = "SCOOP_SIGNER_ED25519_PRIVATE_KEY_FILE"
+	EnvSignerEd25519PublicKeyFiles = "SCOOP_SIGNER_ED25519_PUBLIC_KEY_FILES" // comma separated
+	EnvSignerExpiry                = "SCOOP_SIGNER_EXPIRY"                   // e.g. "5m"
+	EnvSignerNonces                = "SCOOP_SIGNER_NONCES"                   // true or false
+	EnvSignerRequireNonce          = "SCOOP_SIGNER_REQUIRE_NONCE"            // true or false
+	EnvSignerAllowFake             = "SCOOP_SIGNER_ALLOW_FAKE"               // true or false
+)
+
+// ScoopSignerConfigFromEnv reads a ScoopSignerConfig from the SCOOP_SIGNER_*
+// environment variables. Unset variables leave their fields at the zero value.
+func ScoopSignerConfigFromEnv() (ScoopSignerConfig, error) {
+	cfg := ScoopSignerConfig{
+		Mode:                  os.Getenv(EnvSignerMode),
+		HMACKeyFile:           os.Getenv(EnvSignerHMACKeyFile),
+		HMACPrimaryKeyID:      os.Getenv(EnvSignerHMACPrimaryKeyID),
+		Ed25519PrivateKeyFile: os.Getenv(EnvSignerEd25519PrivateKeyFile),
+	}
+	if pairs := os.Getenv(EnvSignerHMACKeyFiles); pairs != "" {
+		cfg.HMACKeyFiles = make(map[string]string)
+		for _, pair := range strings.Split(pairs, ",") {
+			if pair = strings.TrimSpace(pair); pair == "" {
+				continue
+			}
+			i := strings.IndexByte(pair, '=')
+			if i <= 0 || i == len(pair)-1 {
+				return cfg, fmt.Errorf("%s: %q is not an id=path pair", EnvSignerHMACKeyFiles, pair)
+			}
+			cfg.HMACKeyFiles[pair[:i]] = pair[i+1:]
+		}
+	}
+	if files := os.Getenv(EnvSignerEd25519PublicKeyFiles); files != "" {
+		for _, f := range strings.Split(files, ",") {
+			if f = strings.TrimSpace(f); f != "" {
+				cfg.Ed25519PublicKeyFiles = append(cfg.Ed25519PublicKeyFiles, f)
+			}
+		}
+	}
+
+	var err error
+	if v := os.Getenv(EnvSignerExpiry); v != "" {
+		if cfg.Expiry, err = time.ParseDuration(v); err != nil {
+			return cfg, fmt.Errorf("%s: %v", EnvSignerExpiry, err)
+		}
+	}
+	if v := os.Getenv(EnvSignerNonces); v != "" {
+		if cfg.Nonces, err = strconv.ParseBool(v); err != nil {
+			return cfg, fmt.Errorf("%s: %v", EnvSignerNonces, err)
+		}
+	}
+	if v := os.Getenv(EnvSignerRequireNonce); v != "" {
+		if cfg.RequireNonce, err = strconv.ParseBool(v); err != nil {
+			return cfg, fmt.Errorf("%s: %v", EnvSignerRequireNonce, err)
+		}
+	}
+	if v := os.Getenv(EnvSignerAllowFake); v != "" {
+		if cfg.AllowFake, err = strconv.ParseBool(v); err != nil {
+			return cfg, fmt.Errorf("%s: %v", EnvSignerAllowFake, err)
+		}
+	}
+	return cfg, nil
+}
+
+// Validate checks that the config describes a signer, without reading the key files.
+func (c *ScoopSignerConfig) Validate() error {
+	hasHMACKeys := c.HMACKeyFile != "" || len(c.HMACKeyFiles) > 0
+	hasEd25519Keys := c.Ed25519PrivateKeyFile != "" || len(c.Ed25519PublicKeyFiles) > 0
+	switch c.Mode {
+	case "":
+		return errors.New("signer mode is not set")
+	case SignerModeFake:
+		if !c.AllowFake {
+			return errors.New("fake signer is not allowed")
+		}
+		if hasHMACKeys || hasEd25519Keys {
+			return errors.New("fake signer does not use keys")
+		}
+		if c.Nonces || c.RequireNonce {
+			return errors.New("fake signer does not use nonces")
+		}
+		return nil
+	case SignerModeHMAC:
+		if !hasHMACKeys {
+			return errors.New("hmac signer needs a key file")
+		}
+		if c.HMACKeyFile != "" && len(c.HMACKeyFiles) > 0 {
+			return errors.New("hmac signer takes a key file or keyed key files, not both")
+		}
+		if len(c.HMACKeyFiles) > 0 {
+			if _, ok := c.HMACKeyFiles[c.HMACPrimaryKeyID]; !ok {
+				return fmt.Errorf("hmac primary key %q is not one of the key files", c.HMACPrimaryKeyID)
+			}
+		} else if c.HMACPrimaryKeyID != "" {
+			return errors.New("hmac primary key is set without keyed key files")
+		}
+		if hasEd25519Keys {
+			return errors.New("hmac signer does not use ed25519 keys")
+		}
+	case SignerModeEd25519:
+		if !hasEd25519Keys {
+			return errors.New("ed25519 signer needs a private key file or public key files")
+		}
+		if hasHMACKeys {
+			return errors.New("ed25519 signer does not use an hmac key")
+		}
+	default:
+		return fmt.Errorf("unknown signer mode %q", c.Mode)
+	}
+	if c.Expiry <= 0 {
+		return errors.New("signer expiry must be a positive duration")
+	}
+	if c.RequireNonce && !c.Nonces {
+		return errors.New("signer cannot require nonces without Nonces")
+	}
+	return nil
+}
+
+// NewScoopSigner returns the ScoopSigner described by cfg.
+func NewScoopSigner(cfg ScoopSignerConfig) (ScoopSigner, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	var nonces msg_signer.NonceStore
+	if cfg.Nonces {
+		nonces = msg_signer.NewMemoryNonceStore(0)
+	}
+
+	switch cfg.Mode {
+	case SignerModeFake:
+		return &FakeScoopSigner{}, nil
+	case SignerModeHMAC:
+		var signer *msg_signer.TimeSigner
+		if len(cfg.HMACKeyFiles) > 0 {
+			keyring, err := readKeyring(cfg.HMACKeyFiles, cfg.HMACPrimaryKeyID)
+			if err != nil {
+				return nil, err
+			}
+			signer = msg_signer.NewKeyringTimeSigner(keyring)
+		} else {
+			secret, err := readHMACKey(cfg.HMACKeyFile)
+			if err != nil {
+				return nil, err
+			}
+			signer = msg_signer.NewTimeSignerFunc(func() hash.Hash { return hmac.New(sha256.New, secret) })
+		}
+		return &AuthScoopSigner{
+			TimeSigner:   signer,
+			Exp:          cfg.Expiry,
+			Nonces:       nonces,
+			RequireNonce: cfg.RequireNonce,
+		}, nil
+	default:
+		var priv ed25519.PrivateKey
+		if cfg.Ed25519PrivateKeyFile != "" {
+			var err error
+			if priv, err = readEd25519PrivateKey(cfg.Ed25519PrivateKeyFile); err != nil {
+				return nil, err
+			}
+		}
+		pubs := make([]ed25519.PublicKey, 0, len(cfg.Ed25519PublicKeyFiles))
+		for _, f := range cfg.Ed25519PublicKeyFiles {
+			pub, err := readEd25519PublicKey(f)
+			if err != nil {
+				return nil, err
+			}
+			pubs = append(pubs, pub)
+		}
+		s, err := NewEd25519ScoopSigner(priv, pubs, cfg.Expiry)
+		if err != nil {
+			return nil, err
+		}
+		s.Nonces = nonces
+		s.RequireNonce = cfg.RequireNonce
+		return s, nil
+	}
+}
+
+func readHMACKey(path string) ([]byte, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading hmac key: %v", err)
+	}
+	b = bytes.TrimSpace(b)
+	if len(b) < MinHMACKeyLength {
+		return nil, fmt.Errorf("hmac key in %s is %d bytes, shorter than %d", path, len(b), MinHMACKeyLength)
+	}
+	return b, nil
+}
+
+// readKeyring returns a Keyring holding the secrets in files, keyed by ID.
+func readKeyring(files map[string]string, primaryID string) (*msg_signer.Keyring, error) {
+	secret, err := readHMACKey(files[primaryID])
+	if err != nil {
+		return nil, err
+	}
+	keyring, err := msg_signer.NewKeyring(primaryID, secret)
+	if err != nil {
+		return nil, err
+	}
+	for id, path := range files {
+		if id == primaryID {
+			continue
+		}
+		if secret, err = readHMACKey(path); err != nil {
+			return nil, err
+		}
+		if err = keyring.Add(id, secret); err != nil {
+			return nil, err
+		}
+	}
+	return keyring, nil
+}
+
+// readPEM returns the contents of the first PEM block of the given type in a file.
+func readPEM(path, blockType string) ([]byte, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		var block *pem.Block
+		block, b = pem.Decode(b)
+		if block == nil {
+			return nil, fmt.Errorf("%s has no %s block", path, blockType)
+		}
+		if block.Type == blockType {
+			return block.Bytes, nil
+		}
+	}
+}
+
+func readEd25519PrivateKey(path string) (ed25519.PrivateKey, error) {
+	der, err := readPEM(path, "PRIVATE KEY")
+	if err != nil {
+		return nil, fmt.Errorf("reading ed25519 private key: %v", err)
+	}
+	key, err := x509.ParsePKCS8PrivateKey(der)
+	if err != nil {
+		return nil, fmt.Errorf("reading ed25519 private key from %s: %v", path, err)
+	}
+	priv, ok := key.(ed25519.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("%s does not hold an ed25519 private key", path)
+	}
+	return priv, nil
+}
+
+func readEd25519PublicKey(path string) (ed25519.PublicKey, error) {
+	der, err := readPEM(path, "PUBLIC KEY")
+	if err != nil {
+		return nil, fmt.Errorf("reading ed25519 public key: %v", err)
+	}
+	key, err := x509.ParsePKIXPublicKey(der)
+	if err != nil {
+		return nil, fmt.Errorf("reading ed25519 public key from %s: %v", path, err)
+	}
+	pub, ok := key.(ed25519.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("%s does not hold an ed25519 public key", path)
+	}
+	return pub, nil
+}
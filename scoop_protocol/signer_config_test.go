@@ -0,0 +1,246 @@
+package scoop_protocol
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeFile(t *testing.T, name string, data []byte) string {
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, ioutil.WriteFile(path, data, 0600))
+	return path
+}
+
+// writeEd25519Keys writes a new key pair as PEM files and returns their paths.
+func writeEd25519Keys(t *testing.T) (privPath, pubPath string) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	privDER, err := x509.MarshalPKCS8PrivateKey(priv)
+	require.NoError(t, err)
+	pubDER, err := x509.MarshalPKIXPublicKey(pub)
+	require.NoError(t, err)
+	privPath = writeFile(t, "key.pem", pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privDER}))
+	pubPath = writeFile(t, "key.pub.pem", pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pubDER}))
+	return privPath, pubPath
+}
+
+func signAndVerify(t *testing.T, signer, verifier ScoopSigner) {
+	b, err := signer.SignJsonBody(RowCopyRequest{"key", "table", 0})
+	require.NoError(t, err)
+	req, err := verifier.GetRowCopyRequest(bytes.NewReader(b))
+	require.NoError(t, err)
+	assert.Equal(t, &RowCopyRequest{"key", "table", 0}, req)
+}
+
+func TestNewScoopSignerHMAC(t *testing.T) {
+	key := writeFile(t, "hmac", []byte(strings.Repeat("k", MinHMACKeyLength)+"\n"))
+	cfg := ScoopSignerConfig{Mode: SignerModeHMAC, HMACKeyFile: key, Expiry: time.Minute}
+	s, err := NewScoopSigner(cfg)
+	require.NoError(t, err)
+	require.IsType(t, &AuthScoopSigner{}, s)
+	signAndVerify(t, s, s)
+
+	// a signer made from the same key accepts its messages, one with a different key does not
+	other, err := NewScoopSigner(cfg)
+	require.NoError(t, err)
+	signAndVerify(t, s, other)
+	cfg.HMACKeyFile = writeFile(t, "hmac", []byte(strings.Repeat("x", MinHMACKeyLength)))
+	other, err = NewScoopSigner(cfg)
+	require.NoError(t, err)
+	b, _ := s.SignBody([]byte("{}"))
+	_, err = other.GetConfig(bytes.NewReader(b))
+	assert.Equal(t, BadVerified, err)
+
+	cfg.HMACKeyFile = writeFile(t, "hmac", []byte("short"))
+	_, err = NewScoopSigner(cfg)
+	assert.Error(t, err)
+	cfg.HMACKeyFile = filepath.Join(t.TempDir(), "missing")
+	_, err = NewScoopSigner(cfg)
+	assert.Error(t, err)
+}
+
+func TestNewScoopSignerKeyring(t *testing.T) {
+	oldKey := writeFile(t, "old", []byte(strings.Repeat("o", MinHMACKeyLength)))
+	newKey := writeFile(t, "new", []byte(strings.Repeat("n", MinHMACKeyLength)))
+	cfg := ScoopSignerConfig{Mode: SignerModeHMAC, HMACKeyFiles: map[string]string{"old": oldKey},
+		HMACPrimaryKeyID: "old", Expiry: time.Minute}
+	oldSender, err := NewScoopSigner(cfg)
+	require.NoError(t, err)
+
+	// rotating: receivers learn the new key, then senders sign with it
+	cfg.HMACKeyFiles = map[string]string{"old": oldKey, "new": newKey}
+	receiver, err := NewScoopSigner(cfg)
+	require.NoError(t, err)
+	cfg.HMACPrimaryKeyID = "new"
+	newSender, err := NewScoopSigner(cfg)
+	require.NoError(t, err)
+	signAndVerify(t, oldSender, receiver)
+	signAndVerify(t, newSender, receiver)
+
+	b, err := newSender.SignBody([]byte("{}"))
+	require.NoError(t, err)
+	_, err = oldSender.GetConfig(bytes.NewReader(b))
+	assert.Equal(t, BadVerified, err, "verified with a key the receiver does not have")
+
+	cfg.HMACKeyFiles["short"] = writeFile(t, "short", []byte("short"))
+	_, err = NewScoopSigner(cfg)
+	assert.Error(t, err)
+}
+
+func TestNewScoopSignerEd25519(t *testing.T) {
+	privPath, pubPath := writeEd25519Keys(t)
+	signer, err := NewScoopSigner(ScoopSignerConfig{Mode: SignerModeEd25519, Ed25519PrivateKeyFile: privPath,
+		Expiry: time.Minute, Nonces: true})
+	require.NoError(t, err)
+	verifier, err := NewScoopSigner(ScoopSignerConfig{Mode: SignerModeEd25519,
+		Ed25519PublicKeyFiles: []string{pubPath}, Expiry: time.Minute, Nonces: true})
+	require.NoError(t, err)
+	signAndVerify(t, signer, verifier)
+
+	b, err := signer.SignBody([]byte("{}"))
+	require.NoError(t, err)
+	_, err = verifier.GetConfig(bytes.NewReader(b))
+	require.NoError(t, err)
+	_, err = verifier.GetConfig(bytes.NewReader(b))
+	assert.Equal(t, ReplayedMessage, err)
+
+	// keys of the wrong kind or in the wrong file
+	for _, cfg := range []ScoopSignerConfig{
+		{Mode: SignerModeEd25519, Ed25519PrivateKeyFile: pubPath, Expiry: time.Minute},
+		{Mode: SignerModeEd25519, Ed25519PublicKeyFiles: []string{privPath}, Expiry: time.Minute},
+		{Mode: SignerModeEd25519, Ed25519PublicKeyFiles: []string{writeFile(t, "junk", []byte("junk"))}, Expiry: time.Minute},
+	} {
+		_, err := NewScoopSigner(cfg)
+		assert.Error(t, err, "%+v", cfg)
+	}
+}
+
+func TestNewScoopSignerRequireNonce(t *testing.T) {
+	key := writeFile(t, "hmac", []byte(strings.Repeat("k", MinHMACKeyLength)))
+	privPath, pubPath := writeEd25519Keys(t)
+	for _, pair := range [][2]ScoopSignerConfig{
+		{
+			{Mode: SignerModeHMAC, HMACKeyFile: key, Expiry: time.Minute},
+			{Mode: SignerModeHMAC, HMACKeyFile: key, Expiry: time.Minute, Nonces: true, RequireNonce: true},
+		},
+		{
+			{Mode: SignerModeEd25519, Ed25519PrivateKeyFile: privPath, Expiry: time.Minute},
+			{Mode: SignerModeEd25519, Ed25519PublicKeyFiles: []string{pubPath}, Expiry: time.Minute,
+				Nonces: true, RequireNonce: true},
+		},
+	} {
+		signer, err := NewScoopSigner(pair[0])
+		require.NoError(t, err)
+		verifier, err := NewScoopSigner(pair[1])
+		require.NoError(t, err)
+
+		b, err := signer.SignBody([]byte("{}"))
+		require.NoError(t, err)
+		_, err = verifier.GetConfig(bytes.NewReader(b))
+		assert.Equal(t, MissingNonce, err, pair[1].Mode)
+	}
+}
+
+func TestNewScoopSignerFake(t *testing.T) {
+	_, err := NewScoopSigner(ScoopSignerConfig{Mode: SignerModeFake})
+	assert.Error(t, err, "fake signer made without AllowFake")
+
+	s, err := NewScoopSigner(ScoopSignerConfig{Mode: SignerModeFake, AllowFake: true})
+	require.NoError(t, err)
+	assert.IsType(t, &FakeScoopSigner{}, s)
+}
+
+func TestScoopSignerConfigValidate(t *testing.T) {
+	for _, cfg := range []ScoopSignerConfig{
+		{},
+		{Mode: "rsa", Expiry: time.Minute},
+		{Mode: SignerModeHMAC, Expiry: time.Minute},
+		{Mode: SignerModeHMAC, HMACKeyFile: "key"},
+		{Mode: SignerModeHMAC, HMACKeyFile: "key", Expiry: -time.Minute},
+		{Mode: SignerModeHMAC, HMACKeyFile: "key", Ed25519PrivateKeyFile: "priv", Expiry: time.Minute},
+		{Mode: SignerModeEd25519, Expiry: time.Minute},
+		{Mode: SignerModeEd25519, Ed25519PrivateKeyFile: "priv"},
+		{Mode: SignerModeEd25519, Ed25519PrivateKeyFile: "priv", HMACKeyFile: "key", Expiry: time.Minute},
+		{Mode: SignerModeFake, AllowFake: true, HMACKeyFile: "key"},
+		{Mode: SignerModeFake, AllowFake: true, Nonces: true},
+		{Mode: SignerModeFake, AllowFake: true, HMACKeyFiles: map[string]string{"a": "key"}, HMACPrimaryKeyID: "a"},
+		{Mode: SignerModeHMAC, HMACKeyFiles: map[string]string{"a": "key"}, Expiry: time.Minute},
+		{Mode: SignerModeHMAC, HMACKeyFiles: map[string]string{"a": "key"}, HMACPrimaryKeyID: "b", Expiry: time.Minute},
+		{Mode: SignerModeHMAC, HMACKeyFile: "key", HMACKeyFiles: map[string]string{"a": "key"}, HMACPrimaryKeyID: "a",
+			Expiry: time.Minute},
+		{Mode: SignerModeHMAC, HMACKeyFile: "key", HMACPrimaryKeyID: "a", Expiry: time.Minute},
+		{Mode: SignerModeEd25519, Ed25519PrivateKeyFile: "priv", HMACKeyFiles: map[string]string{"a": "key"},
+			Expiry: time.Minute},
+		{Mode: SignerModeHMAC, HMACKeyFile: "key", Expiry: time.Minute, RequireNonce: true},
+	} {
+		assert.Error(t, cfg.Validate(), "%+v", cfg)
+	}
+
+	for _, cfg := range []ScoopSignerConfig{
+		{Mode: SignerModeHMAC, HMACKeyFile: "key", Expiry: time.Minute},
+		{Mode: SignerModeHMAC, HMACKeyFile: "key", Expiry: time.Minute, Nonces: true, RequireNonce: true},
+		{Mode: SignerModeHMAC, HMACKeyFiles: map[string]string{"a": "key", "b": "key"}, HMACPrimaryKeyID: "b",
+			Expiry: time.Minute},
+		{Mode: SignerModeEd25519, Ed25519PublicKeyFiles: []string{"pub"}, Expiry: time.Minute},
+		{Mode: SignerModeFake, AllowFake: true},
+	} {
+		assert.NoError(t, cfg.Validate(), "%+v", cfg)
+	}
+}
+
+func TestScoopSignerConfigFromEnv(t *testing.T) {
+	t.Setenv(EnvSignerMode, SignerModeEd25519)
+	t.Setenv(EnvSignerEd25519PrivateKeyFile, "/keys/priv.pem")
+	t.Setenv(EnvSignerEd25519PublicKeyFiles, "/keys/a.pem, /keys/b.pem,")
+	t.Setenv(EnvSignerExpiry, "5m")
+	t.Setenv(EnvSignerNonces, "true")
+	t.Setenv(EnvSignerRequireNonce, "true")
+	cfg, err := ScoopSignerConfigFromEnv()
+	require.NoError(t, err)
+	assert.Equal(t, ScoopSignerConfig{
+		Mode:                  SignerModeEd25519,
+		Ed25519PrivateKeyFile: "/keys/priv.pem",
+		Ed25519PublicKeyFiles: []string{"/keys/a.pem", "/keys/b.pem"},
+		Expiry:                5 * time.Minute,
+		Nonces:                true,
+		RequireNonce:          true,
+	}, cfg)
+
+	t.Run("keyring", func(t *testing.T) {
+		t.Setenv(EnvSignerMode, SignerModeHMAC)
+		t.Setenv(EnvSignerEd25519PrivateKeyFile, "")
+		t.Setenv(EnvSignerEd25519PublicKeyFiles, "")
+		t.Setenv(EnvSignerHMACKeyFiles, "2016-01=/keys/old, 2016-07=/keys/new")
+		t.Setenv(EnvSignerHMACPrimaryKeyID, "2016-07")
+		cfg, err := ScoopSignerConfigFromEnv()
+		require.NoError(t, err)
+		assert.Equal(t, map[string]string{"2016-01": "/keys/old", "2016-07": "/keys/new"}, cfg.HMACKeyFiles)
+		assert.Equal(t, "2016-07", cfg.HMACPrimaryKeyID)
+		assert.NoError(t, cfg.Validate())
+	})
+
+	for env, value := range map[string]string{
+		EnvSignerHMACKeyFiles: "/keys/old",
+		EnvSignerExpiry:       "five minutes",
+		EnvSignerNonces:       "sure",
+		EnvSignerRequireNonce: "yes please",
+		EnvSignerAllowFake:    "maybe",
+	} {
+		t.Run(env, func(t *testing.T) {
+			t.Setenv(env, value)
+			_, err := ScoopSignerConfigFromEnv()
+			assert.Error(t, err)
+		})
+	}
+}
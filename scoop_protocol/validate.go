@@ -0,0 +1,108 @@
+package scoop_protocol
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/twitchscience/scoop_protocol/transformer"
+)
+
+// ColumnErrors holds every problem found while validating a Config, keyed by the
+// OutboundName of the offending column. Columns without an OutboundName are keyed by
+// their position, e.g. "#2", and problems with the Config itself are keyed by "".
+type ColumnErrors map[string][]error
+
+func (e ColumnErrors) Error() string {
+	keys := make([]string, 0, len(e))
+	for k := range e {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	msgs := make([]string, 0, len(keys))
+	for _, k := range keys {
+		for _, err := range e[k] {
+			if k == "" {
+				msgs = append(msgs, err.Error())
+			} else {
+				msgs = append(msgs, fmt.Sprintf("%s: %v", k, err))
+			}
+		}
+	}
+	return strings.Join(msgs, "; ")
+}
+
+func (e ColumnErrors) add(column string, err error) {
+	e[column] = append(e[column], err)
+}
+
+// Validate checks the Config for problems that would otherwise only show up when the
+// event is loaded. It returns nil or a ColumnErrors describing all of them.
+func (c *Config) Validate() error {
+	errs := make(ColumnErrors)
+	if c.EventName == "" {
+		errs.add("", errors.New("event name is empty"))
+	}
+
+	outbound := make(map[string]bool, len(c.Columns))
+	inbound := make(map[string]bool, len(c.Columns))
+	for _, col := range c.Columns {
+		inbound[col.InboundName] = true
+	}
+
+	for i, col := range c.Columns {
+		key := col.OutboundName
+		if key == "" {
+			key = fmt.Sprintf("#%d", i)
+			errs.add(key, errors.New("outbound name is empty"))
+		} else if outbound[key] {
+			errs.add(key, errors.New("outbound name is used by more than one column"))
+		}
+		outbound[key] = true
+
+		if col.InboundName == "" {
+			errs.add(key, errors.New("inbound name is empty"))
+		}
+		for _, err := range validateTransformer(col, inbound) {
+			errs.add(key, err)
+		}
+	}
+
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
+
+func validateTransformer(col ColumnDefinition, inbound map[string]bool) []error {
+	t, ok := transformer.Lookup(col.Transformer)
+	if !ok {
+		return []error{fmt.Errorf("unknown transformer %q", col.Transformer)}
+	}
+
+	var errs []error
+	opts, err := transformer.ParseColumnOptions(col.ColumnCreationOptions)
+	if err != nil {
+		errs = append(errs, err)
+	} else if t.Requires(transformer.OptionLength) && opts.Length == 0 {
+		errs = append(errs, fmt.Errorf("%s column needs a length", col.Transformer))
+	} else if !t.Requires(transformer.OptionLength) && opts.Length != 0 {
+		errs = append(errs, fmt.Errorf("%s column cannot have a length", col.Transformer))
+	}
+
+	if t.Mapping {
+		if col.SupportingColumns == "" {
+			return append(errs, fmt.Errorf("%s column needs supporting columns", col.Transformer))
+		}
+		for _, name := range strings.Split(col.SupportingColumns, ",") {
+			if name = strings.TrimSpace(name); !inbound[name] {
+				errs = append(errs, fmt.Errorf("supporting column %q is not an inbound column", name))
+			}
+		}
+	} else if col.SupportingColumns != "" {
+		errs = append(errs, fmt.Errorf("%s column cannot have supporting columns", col.Transformer))
+	}
+	return errs
+}
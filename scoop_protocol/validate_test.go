@@ -0,0 +1,57 @@
+package scoop_protocol
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateConfig(t *testing.T) {
+	assert.NoError(t, baseOperationsConfig.Validate())
+
+	cfg := Config{
+		EventName: "pageview",
+		Columns: []ColumnDefinition{
+			{"time", "time", "f@timestamp@unix", " sortkey", ""},
+			{"login", "login", "varchar", "(32)", ""},
+			{"user_id", "user_id", "userIDWithMapping", "", "login"},
+			{"country", "country", "ipCountry", "", ""},
+		},
+	}
+	assert.NoError(t, cfg.Validate())
+}
+
+func TestValidateConfigErrors(t *testing.T) {
+	cfg := Config{
+		Columns: []ColumnDefinition{
+			{"time", "time", "f@timestamp@unix", "(32)", ""},
+			{"login", "login", "varchar", "", ""},
+			{"user_id", "user_id", "userIDWithMapping", "", ""},
+			{"channel_id", "channel_id", "userIDWithMapping", "", "login,channel"},
+			{"game", "game", "string", "(32)", ""},
+			{"", "login", "varchar", "(32) sortkey sortkey", ""},
+			{"platform", "", "varchar", "(32)", "login"},
+		},
+	}
+
+	err := cfg.Validate()
+	require.Error(t, err)
+	errs, ok := err.(ColumnErrors)
+	require.True(t, ok, "expected ColumnErrors, got %T", err)
+
+	expected := map[string]int{
+		"":           1, // missing event name
+		"time":       1, // length on a timestamp
+		"login":      4, // varchar without length, duplicate name, empty inbound, bad options
+		"user_id":    1, // no supporting columns
+		"channel_id": 1, // unknown supporting column
+		"game":       1, // unknown transformer
+		"#6":         2, // empty outbound, supporting columns on varchar
+	}
+	for column, n := range expected {
+		assert.Len(t, errs[column], n, column)
+	}
+	assert.Len(t, errs, len(expected))
+	assert.Contains(t, err.Error(), `game: unknown transformer "string"`)
+}
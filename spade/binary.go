@@ -0,0 +1,170 @@
+package spade
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"time"
+)
+
+// BINARY_VERSION is the first byte of every event encoded by MarshalBinary.
+const BINARY_VERSION byte = 1
+
+// BINARY_ENCODING is set in the version byte of data made by Compress with
+// WithBinaryEncoding, to mark the compressed event as encoded by MarshalBinary rather
+// than Marshal. The remaining bits name the codec.
+const BINARY_ENCODING byte = 0x80
+
+const binaryHasTime = 1 << 0
+
+// MarshalBinary encodes an event more compactly than Marshal. The layout is
+//
+//	version byte (BINARY_VERSION)
+//	flags byte (bit 0: ReceivedAt is set)
+//	ReceivedAt as big-endian int64 nanoseconds since the epoch, if set
+//	IP length byte (0, 4 or 16) and the IP's bytes
+//	XForwardedFor, Uuid, Data, UserAgent and EdgeType, each a uvarint length and bytes
+//	Version as a varint
+//
+// The time zone of ReceivedAt is not kept, and UnmarshalBinary returns it in UTC.
+func MarshalBinary(src *Event) ([]byte, error) {
+	size := 2 + 8 + 1 + net.IPv6len + 5*binary.MaxVarintLen64 + binary.MaxVarintLen64 +
+		len(src.XForwardedFor) + len(src.Uuid) + len(src.Data) + len(src.UserAgent) + len(src.EdgeType)
+	b := make([]byte, 0, size)
+	b = append(b, BINARY_VERSION)
+
+	if src.ReceivedAt.IsZero() {
+		b = append(b, 0)
+	} else {
+		nanos := src.ReceivedAt.UnixNano()
+		if !time.Unix(0, nanos).Equal(src.ReceivedAt) {
+			return nil, fmt.Errorf("receivedAt %v cannot be held in int64 nanoseconds", src.ReceivedAt)
+		}
+		b = append(b, binaryHasTime)
+		b = binary.BigEndian.AppendUint64(b, uint64(nanos))
+	}
+
+	ip := src.ClientIp
+	if ip4 := ip.To4(); ip4 != nil {
+		ip = ip4
+	}
+	switch len(ip) {
+	case 0, net.IPv4len, net.IPv6len:
+	default:
+		return nil, fmt.Errorf("clientIp has invalid length %d", len(ip))
+	}
+	b = append(b, byte(len(ip)))
+	b = append(b, ip...)
+
+	for _, s := range []string{src.XForwardedFor, src.Uuid, src.Data, src.UserAgent, src.EdgeType} {
+		b = binary.AppendUvarint(b, uint64(len(s)))
+		b = append(b, s...)
+	}
+	return binary.AppendVarint(b, int64(src.Version)), nil
+}
+
+var errBinaryTruncated = errors.New("binary event is truncated")
+
+// UnmarshalBinary decodes an event encoded by MarshalBinary into dst and upgrades it to
+// PROTOCOL_VERSION.
+func UnmarshalBinary(b []byte, dst *Event) error {
+	d := binaryDecoder{b: b}
+	if v := d.byte(); d.err == nil && v != BINARY_VERSION {
+		return fmt.Errorf("unknown binary event version: got %v expected %v", v, BINARY_VERSION)
+	}
+
+	var e Event
+	if flags := d.byte(); flags&binaryHasTime != 0 {
+		e.ReceivedAt = time.Unix(0, int64(d.uint64())).UTC()
+	}
+	switch n := int(d.byte()); n {
+	case 0:
+	case net.IPv4len, net.IPv6len:
+		e.ClientIp = net.IP(d.bytes(n))
+	default:
+		if d.err == nil {
+			return fmt.Errorf("clientIp has invalid length %d", n)
+		}
+	}
+	for _, s := range []*string{&e.XForwardedFor, &e.Uuid, &e.Data, &e.UserAgent, &e.EdgeType} {
+		*s = d.string()
+	}
+	e.Version = d.int()
+
+	if d.err != nil {
+		return d.err
+	}
+	if len(d.b) > 0 {
+		return fmt.Errorf("binary event has %d trailing bytes", len(d.b))
+	}
+	*dst = e
+	return Upgrade(dst)
+}
+
+// binaryDecoder reads the parts of a binary event from b. After the first error every
+// read returns a zero value, so only err needs checking at the end.
+type binaryDecoder struct {
+	b   []byte
+	err error
+}
+
+func (d *binaryDecoder) bytes(n int) []byte {
+	if d.err != nil {
+		return nil
+	}
+	if n < 0 || n > len(d.b) {
+		d.err = errBinaryTruncated
+		return nil
+	}
+	v := make([]byte, n)
+	copy(v, d.b)
+	d.b = d.b[n:]
+	return v
+}
+
+func (d *binaryDecoder) byte() byte {
+	if v := d.bytes(1); v != nil {
+		return v[0]
+	}
+	return 0
+}
+
+func (d *binaryDecoder) uint64() uint64 {
+	if v := d.bytes(8); v != nil {
+		return binary.BigEndian.Uint64(v)
+	}
+	return 0
+}
+
+func (d *binaryDecoder) string() string {
+	if d.err != nil {
+		return ""
+	}
+	n, size := binary.Uvarint(d.b)
+	if size <= 0 || n > uint64(len(d.b)-size) {
+		d.err = errBinaryTruncated
+		return ""
+	}
+	s := string(d.b[size : size+int(n)])
+	d.b = d.b[size+int(n):]
+	return s
+}
+
+func (d *binaryDecoder) int() int {
+	if d.err != nil {
+		return 0
+	}
+	v, size := binary.Varint(d.b)
+	if size == 0 {
+		d.err = errBinaryTruncated
+		return 0
+	}
+	if size < 0 || v < math.MinInt32 || v > math.MaxInt32 {
+		d.err = errors.New("binary event has an invalid record version")
+		return 0
+	}
+	d.b = d.b[size:]
+	return int(v)
+}
@@ -0,0 +1,108 @@
+package spade
+
+import (
+	"fmt"
+	"net"
+	"strings"
+)
+
+// TrustedProxies is a set of networks holding proxies, such as load balancers, whose
+// X-Forwarded-For entries can be believed.
+type TrustedProxies []*net.IPNet
+
+// ParseTrustedProxies parses a list of CIDRs, e.g. "10.0.0.0/8". A bare address stands
+// for a network holding only that address.
+func ParseTrustedProxies(cidrs []string) (TrustedProxies, error) {
+	proxies := make(TrustedProxies, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		cidr = strings.TrimSpace(cidr)
+		if !strings.Contains(cidr, "/") {
+			ip := net.ParseIP(cidr)
+			if ip == nil {
+				return nil, fmt.Errorf("%q is not a CIDR or IP address", cidr)
+			}
+			bits := net.IPv6len * 8
+			if ip4 := ip.To4(); ip4 != nil {
+				ip, bits = ip4, net.IPv4len*8
+			}
+			proxies = append(proxies, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, err
+		}
+		proxies = append(proxies, network)
+	}
+	return proxies, nil
+}
+
+// DefaultTrustedProxies returns the loopback and private networks, where the load
+// balancers in front of the edge usually live. Each call returns a new set.
+func DefaultTrustedProxies() TrustedProxies {
+	p, err := ParseTrustedProxies([]string{
+		"127.0.0.0/8", "10.0.0.0/8", "172.16.0.0/12", "192.168.0.0/16", "::1/128", "fc00::/7",
+	})
+	if err != nil {
+		panic(err)
+	}
+	return p
+}
+
+// Contains returns true if ip is in one of the networks.
+func (p TrustedProxies) Contains(ip net.IP) bool {
+	for _, network := range p {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// ClientIP returns the address of the client that a request from remote with the given
+// X-Forwarded-For header came from. It walks the header from the right, starting at
+// remote, for as long as the addresses are trusted proxies, and returns the first one
+// that is not. If the header runs out, or has an entry that is not an address, the
+// last address reached is returned: that is as far as the chain can be believed. IPv4
+// addresses are returned in their 4-byte form.
+func (p TrustedProxies) ClientIP(remote net.IP, xForwardedFor string) net.IP {
+	ip := remote
+	if xForwardedFor != "" && p.Contains(ip) {
+		hops := strings.Split(xForwardedFor, ",")
+		for i := len(hops) - 1; i >= 0; i-- {
+			hop := parseForwardedIP(hops[i])
+			if hop == nil {
+				break
+			}
+			ip = hop
+			if !p.Contains(ip) {
+				break
+			}
+		}
+	}
+	if ip4 := ip.To4(); ip4 != nil {
+		return ip4
+	}
+	return ip
+}
+
+// parseForwardedIP parses an X-Forwarded-For entry, which some proxies write with a port.
+func parseForwardedIP(s string) net.IP {
+	s = strings.TrimSpace(s)
+	if ip := net.ParseIP(s); ip != nil {
+		return ip
+	}
+	if host, _, err := net.SplitHostPort(s); err == nil {
+		return net.ParseIP(host)
+	}
+	return net.ParseIP(strings.TrimSuffix(strings.TrimPrefix(s, "["), "]"))
+}
+
+// EffectiveClientIP returns the address of the client that sent the event, found from
+// ClientIp and XForwardedFor with proxies. If proxies is empty, ClientIp is returned.
+func (e *Event) EffectiveClientIP(proxies TrustedProxies) net.IP {
+	if len(proxies) == 0 {
+		return e.ClientIp
+	}
+	return proxies.ClientIP(e.ClientIp, e.XForwardedFor)
+}
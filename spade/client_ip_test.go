@@ -0,0 +1,71 @@
+package spade
+
+import (
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseTrustedProxies(t *testing.T) {
+	p, err := ParseTrustedProxies([]string{"10.0.0.0/8", " 203.0.113.7 ", "2001:db8::/32", "2001:db8:1::1"})
+	require.NoError(t, err)
+	assert.True(t, p.Contains(net.ParseIP("10.1.2.3")))
+	assert.True(t, p.Contains(net.ParseIP("203.0.113.7")))
+	assert.False(t, p.Contains(net.ParseIP("203.0.113.8")))
+	assert.True(t, p.Contains(net.ParseIP("2001:db8:ffff::1")))
+	assert.False(t, p.Contains(net.ParseIP("2001:db9::1")))
+
+	for _, bad := range []string{"", "10.0.0.0/33", "example.com"} {
+		_, err := ParseTrustedProxies([]string{bad})
+		assert.Error(t, err, bad)
+	}
+}
+
+func TestClientIP(t *testing.T) {
+	p, err := ParseTrustedProxies([]string{"10.0.0.0/8", "203.0.113.0/24"})
+	require.NoError(t, err)
+
+	tests := []struct {
+		remote        string
+		xForwardedFor string
+		expected      string
+	}{
+		// requests straight from a client, whatever they claim
+		{"81.2.69.160", "", "81.2.69.160"},
+		{"81.2.69.160", "1.1.1.1", "81.2.69.160"},
+		// through one or more trusted proxies
+		{"10.0.0.1", "81.2.69.160", "81.2.69.160"},
+		{"10.0.0.1", "81.2.69.160, 203.0.113.5", "81.2.69.160"},
+		{"10.0.0.1", "  81.2.69.160 ,203.0.113.5 ", "81.2.69.160"},
+		// a spoofed entry left of the real client is ignored
+		{"10.0.0.1", "1.1.1.1, 81.2.69.160", "81.2.69.160"},
+		// ports and IPv6
+		{"10.0.0.1", "81.2.69.160:4321", "81.2.69.160"},
+		{"10.0.0.1", "[2001:db8::1]:4321", "2001:db8::1"},
+		{"10.0.0.1", "2001:db8::1", "2001:db8::1"},
+		// the chain stops at garbage, or when it runs out
+		{"10.0.0.1", "81.2.69.160, unknown", "10.0.0.1"},
+		{"10.0.0.1", "81.2.69.160, unknown, 203.0.113.5", "203.0.113.5"},
+		{"10.0.0.1", "10.0.0.2, 203.0.113.5", "10.0.0.2"},
+		{"10.0.0.1", "", "10.0.0.1"},
+	}
+	for _, tt := range tests {
+		ip := p.ClientIP(net.ParseIP(tt.remote), tt.xForwardedFor)
+		assert.Equal(t, net.ParseIP(tt.expected).String(), ip.String(), "%s via %q", tt.remote, tt.xForwardedFor)
+	}
+
+	assert.Len(t, p.ClientIP(net.ParseIP("81.2.69.160"), ""), net.IPv4len)
+	assert.Nil(t, p.ClientIP(nil, ""))
+}
+
+func TestEffectiveClientIP(t *testing.T) {
+	proxies := DefaultTrustedProxies()
+	e := &Event{ClientIp: net.ParseIP("172.16.4.4"), XForwardedFor: "81.2.69.160, 192.168.0.1"}
+	assert.Equal(t, net.IP{81, 2, 69, 160}, e.EffectiveClientIP(proxies))
+	assert.Equal(t, e.ClientIp, e.EffectiveClientIP(nil), "X-Forwarded-For was believed without proxies")
+
+	e = &Event{ClientIp: net.ParseIP("8.8.8.8"), XForwardedFor: "81.2.69.160"}
+	assert.Equal(t, net.IP{8, 8, 8, 8}, e.EffectiveClientIP(proxies))
+}
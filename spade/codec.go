@@ -0,0 +1,223 @@
+package spade
+
+import (
+	"compress/flate"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+
+	"github.com/golang/snappy"
+	"github.com/klauspost/compress/zstd"
+)
+
+// Version bytes of the built-in codecs, besides COMPRESSION_VERSION which is flate.
+const (
+	GZIP_COMPRESSION_VERSION   byte = 2
+	ZSTD_COMPRESSION_VERSION   byte = 3
+	SNAPPY_COMPRESSION_VERSION byte = 4
+)
+
+// Codec is a compression format for events and globs. Compressed data starts with the
+// codec's Version byte, so readers can tell which codec to use.
+type Codec struct {
+	Version byte
+	Name    string
+	// NewWriter returns a writer compressing into w. Closing it must flush everything,
+	// but not close w. If it has a Reset(io.Writer) method, GlobWriter reuses it.
+	NewWriter func(w io.Writer) (io.WriteCloser, error)
+	// NewReader returns a reader decompressing r. Closing it must not close r.
+	NewReader func(r io.Reader) (io.ReadCloser, error)
+}
+
+var (
+	codecsMu sync.RWMutex
+	codecs   = make(map[byte]Codec)
+)
+
+func init() {
+	for _, c := range []Codec{
+		{
+			Version: COMPRESSION_VERSION,
+			Name:    "flate",
+			NewWriter: func(w io.Writer) (io.WriteCloser, error) {
+				return flate.NewWriter(w, flate.BestCompression)
+			},
+			NewReader: func(r io.Reader) (io.ReadCloser, error) {
+				return flate.NewReader(r), nil
+			},
+		},
+		{
+			Version: GZIP_COMPRESSION_VERSION,
+			Name:    "gzip",
+			NewWriter: func(w io.Writer) (io.WriteCloser, error) {
+				return gzip.NewWriterLevel(w, gzip.DefaultCompression)
+			},
+			NewReader: func(r io.Reader) (io.ReadCloser, error) {
+				return gzip.NewReader(r)
+			},
+		},
+		{
+			Version:   ZSTD_COMPRESSION_VERSION,
+			Name:      "zstd",
+			NewWriter: newZstdWriter,
+			NewReader: func(r io.Reader) (io.ReadCloser, error) {
+				d, err := zstd.NewReader(r, zstd.WithDecoderConcurrency(1))
+				if err != nil {
+					return nil, err
+				}
+				return d.IOReadCloser(), nil
+			},
+		},
+		{
+			Version: SNAPPY_COMPRESSION_VERSION,
+			Name:    "snappy",
+			NewWriter: func(w io.Writer) (io.WriteCloser, error) {
+				return snappy.NewBufferedWriter(w), nil
+			},
+			NewReader: func(r io.Reader) (io.ReadCloser, error) {
+				return io.NopCloser(snappy.NewReader(r)), nil
+			},
+		},
+	} {
+		if err := RegisterCodec(c); err != nil {
+			panic(err)
+		}
+	}
+}
+
+// zstdEncoders holds idle zstd encoders. Making one allocates its tables and buffers,
+// which would otherwise dominate compressing a single event.
+var zstdEncoders sync.Pool
+
+// zstdWriter borrows an encoder from zstdEncoders and returns it when closed.
+type zstdWriter struct {
+	enc *zstd.Encoder
+}
+
+func newZstdWriter(w io.Writer) (io.WriteCloser, error) {
+	z := &zstdWriter{}
+	if err := z.reset(w); err != nil {
+		return nil, err
+	}
+	return z, nil
+}
+
+func (z *zstdWriter) reset(w io.Writer) error {
+	if z.enc != nil {
+		z.enc.Reset(w)
+		return nil
+	}
+	if enc, ok := zstdEncoders.Get().(*zstd.Encoder); ok {
+		enc.Reset(w)
+		z.enc = enc
+		return nil
+	}
+	enc, err := zstd.NewWriter(w, zstd.WithEncoderConcurrency(1))
+	if err != nil {
+		return err
+	}
+	z.enc = enc
+	return nil
+}
+
+func (z *zstdWriter) Write(p []byte) (int, error) {
+	if z.enc == nil {
+		return 0, errors.New("zstd writer is closed")
+	}
+	return z.enc.Write(p)
+}
+
+// Close finishes the stream and returns the encoder to the pool.
+func (z *zstdWriter) Close() error {
+	if z.enc == nil {
+		return nil
+	}
+	err := z.enc.Close()
+	z.enc.Reset(nil)
+	zstdEncoders.Put(z.enc)
+	z.enc = nil
+	return err
+}
+
+// Reset starts a new stream into w, borrowing another encoder if the writer was closed,
+// so that GlobWriter can reuse the writer. If no encoder can be made, Write fails.
+func (z *zstdWriter) Reset(w io.Writer) {
+	_ = z.reset(w)
+}
+
+// RegisterCodec makes a codec available to Compress, Decompress, Deglob, GlobReader and
+// GlobWriter. It fails if the codec's version byte is already taken.
+func RegisterCodec(c Codec) error {
+	if c.Version&BINARY_ENCODING != 0 {
+		return fmt.Errorf("codec %d (%s) has the BINARY_ENCODING bit set", c.Version, c.Name)
+	}
+	if c.NewWriter == nil || c.NewReader == nil {
+		return fmt.Errorf("codec %d (%s) needs both a writer and a reader", c.Version, c.Name)
+	}
+	codecsMu.Lock()
+	defer codecsMu.Unlock()
+	if existing, ok := codecs[c.Version]; ok {
+		return fmt.Errorf("codec version %d is already registered to %s", c.Version, existing.Name)
+	}
+	codecs[c.Version] = c
+	return nil
+}
+
+// LookupCodec returns the codec with the given version byte.
+func LookupCodec(version byte) (Codec, bool) {
+	codecsMu.RLock()
+	defer codecsMu.RUnlock()
+	c, ok := codecs[version]
+	return c, ok
+}
+
+// CodecVersions returns the sorted version bytes of all registered codecs.
+func CodecVersions() []byte {
+	codecsMu.RLock()
+	defer codecsMu.RUnlock()
+	versions := make([]byte, 0, len(codecs))
+	for v := range codecs {
+		versions = append(versions, v)
+	}
+	sort.Slice(versions, func(i, j int) bool { return versions[i] < versions[j] })
+	return versions
+}
+
+// CompressOption changes how Compress and GlobWriter compress.
+type CompressOption func(*compressOptions)
+
+type compressOptions struct {
+	version byte
+	binary  bool
+}
+
+// WithCodec selects the codec with the given version byte instead of flate.
+func WithCodec(version byte) CompressOption {
+	return func(o *compressOptions) {
+		o.version = version
+	}
+}
+
+// WithBinaryEncoding makes Compress encode the event with MarshalBinary instead of
+// Marshal. Globs do not support it.
+func WithBinaryEncoding() CompressOption {
+	return func(o *compressOptions) {
+		o.binary = true
+	}
+}
+
+// compressOptionsFor applies opts and looks up the codec they select.
+func compressOptionsFor(opts []CompressOption) (compressOptions, Codec, error) {
+	o := compressOptions{version: COMPRESSION_VERSION}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	c, ok := LookupCodec(o.version)
+	if !ok {
+		return o, Codec{}, fmt.Errorf("unknown compression version %d", o.version)
+	}
+	return o, c, nil
+}
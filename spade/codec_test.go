@@ -0,0 +1,127 @@
+package spade
+
+import (
+	"bytes"
+	"io"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCodecs(t *testing.T) {
+	assert.Equal(t, []byte{COMPRESSION_VERSION, GZIP_COMPRESSION_VERSION, ZSTD_COMPRESSION_VERSION,
+		SNAPPY_COMPRESSION_VERSION}, CodecVersions())
+
+	event := NewEvent(time.Unix(1397768380, 0).UTC(), net.IPv4(10, 0, 0, 0), "xForwardedFor", "uuid",
+		"data", "userAgent", INTERNAL_EDGE)
+	events := globEvents(25)
+
+	for _, version := range CodecVersions() {
+		codec, _ := LookupCodec(version)
+
+		b, err := Compress(event, WithCodec(version))
+		require.NoError(t, err, codec.Name)
+		assert.Equal(t, version, b[0])
+		decompressed, err := Decompress(b)
+		require.NoError(t, err, codec.Name)
+		assert.Equal(t, event, decompressed, codec.Name)
+
+		g, err := NewGlobWriter(globConfig, WithCodec(version))
+		require.NoError(t, err)
+		// twice, to check the compressor survives a flush
+		for i := 0; i < 2; i++ {
+			for _, e := range events {
+				require.NoError(t, g.Add(e))
+			}
+			glob, err := g.Flush()
+			require.NoError(t, err, codec.Name)
+			assert.Equal(t, version, glob[0])
+
+			deglobbed, err := Deglob(glob)
+			require.NoError(t, err, codec.Name)
+			assert.Equal(t, events, deglobbed, codec.Name)
+
+			read, err := readGlob(t, glob, 0)
+			require.NoError(t, err, codec.Name)
+			assert.Equal(t, events, read, codec.Name)
+		}
+	}
+}
+
+func TestZstdEncoderPool(t *testing.T) {
+	events := globEvents(50)
+	var wg sync.WaitGroup
+	for _, e := range events {
+		wg.Add(1)
+		go func(e *Event) {
+			defer wg.Done()
+			b, err := Compress(e, WithCodec(ZSTD_COMPRESSION_VERSION))
+			if !assert.NoError(t, err) {
+				return
+			}
+			decompressed, err := Decompress(b)
+			assert.NoError(t, err)
+			assert.Equal(t, e, decompressed)
+		}(e)
+	}
+	wg.Wait()
+
+	w, err := newZstdWriter(io.Discard)
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+	assert.NoError(t, w.Close(), "closing twice failed")
+	_, err = w.Write([]byte("x"))
+	assert.Error(t, err, "wrote after close")
+}
+
+func TestCompressUnknownCodec(t *testing.T) {
+	_, err := Compress(&Event{}, WithCodec(0))
+	assert.Error(t, err)
+	_, err = NewGlobWriter(globConfig, WithCodec(0))
+	assert.Error(t, err)
+	_, err = Deglob([]byte{0})
+	assert.Contains(t, err.Error(), "unknown version")
+}
+
+func TestRegisterCodec(t *testing.T) {
+	nop := Codec{
+		Name: "none",
+		NewWriter: func(w io.Writer) (io.WriteCloser, error) {
+			return nopWriteCloser{w}, nil
+		},
+		NewReader: func(r io.Reader) (io.ReadCloser, error) {
+			return io.NopCloser(r), nil
+		},
+	}
+
+	nop.Version = COMPRESSION_VERSION
+	assert.Error(t, RegisterCodec(nop), "registered over flate")
+	assert.Error(t, RegisterCodec(Codec{Version: 100, Name: "broken"}))
+	nop.Version = 1 | BINARY_ENCODING
+	assert.Error(t, RegisterCodec(nop), "registered with the binary bit")
+
+	nop.Version = 101
+	require.NoError(t, RegisterCodec(nop))
+	defer func() {
+		codecsMu.Lock()
+		delete(codecs, nop.Version)
+		codecsMu.Unlock()
+	}()
+
+	b, err := Compress(&Event{Uuid: "uuid", Version: PROTOCOL_VERSION}, WithCodec(nop.Version))
+	require.NoError(t, err)
+	assert.True(t, bytes.Contains(b, []byte(`"uuid":"uuid"`)))
+	e, err := Decompress(b)
+	require.NoError(t, err)
+	assert.Equal(t, "uuid", e.Uuid)
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error { return nil }
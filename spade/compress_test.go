@@ -25,7 +25,7 @@ func TestCompression(t *testing.T) {
 
 func TestWrongVersion(t *testing.T) {
 	var err error
-	fakeData := []byte{COMPRESSION_VERSION + 1}
+	fakeData := []byte{0}
 	_, err = Decompress(fakeData)
 	assert.Contains(t, err.Error(), "Unknown version")
 }
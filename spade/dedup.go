@@ -0,0 +1,90 @@
+package spade
+
+import (
+	"container/heap"
+	"sync"
+	"time"
+)
+
+// Deduper detects events seen more than once, such as those sent to two globs by a
+// retrying edge, by their version 7 Uuid. It remembers UUIDs for a window of time behind
+// the newest one it has seen, so its memory is bounded by the event rate, not by how long
+// it runs. It is safe for concurrent use.
+type Deduper struct {
+	window time.Duration
+
+	mu     sync.Mutex
+	seen   map[UUID]struct{}
+	byTime uuidHeap
+	newest time.Time
+}
+
+// NewDeduper returns a Deduper that remembers UUIDs for the given window.
+func NewDeduper(window time.Duration) *Deduper {
+	return &Deduper{window: window, seen: make(map[UUID]struct{})}
+}
+
+// Duplicate returns true if an event with the same Uuid has been seen within the window.
+// Events older than the window are never reported as duplicates, since they could not
+// have been remembered. It fails if Uuid is not a version 7 UUID.
+func (d *Deduper) Duplicate(e *Event) (bool, error) {
+	u, err := ParseUUID(e.Uuid)
+	if err != nil {
+		return false, err
+	}
+	t, err := u.Time()
+	if err != nil {
+		return false, err
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if _, ok := d.seen[u]; ok {
+		return true, nil
+	}
+	if t.After(d.newest) {
+		d.newest = t
+		d.expire()
+	}
+	if t.Before(d.newest.Add(-d.window)) {
+		return false, nil
+	}
+	d.seen[u] = struct{}{}
+	heap.Push(&d.byTime, timedUUID{uuid: u, time: t})
+	return false, nil
+}
+
+// Len returns the number of UUIDs being remembered.
+func (d *Deduper) Len() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return len(d.seen)
+}
+
+// expire forgets the UUIDs that have fallen out of the window.
+func (d *Deduper) expire() {
+	cutoff := d.newest.Add(-d.window)
+	for len(d.byTime) > 0 && d.byTime[0].time.Before(cutoff) {
+		delete(d.seen, heap.Pop(&d.byTime).(timedUUID).uuid)
+	}
+}
+
+type timedUUID struct {
+	uuid UUID
+	time time.Time
+}
+
+// uuidHeap is a container/heap of UUIDs, oldest first.
+type uuidHeap []timedUUID
+
+func (h uuidHeap) Len() int            { return len(h) }
+func (h uuidHeap) Less(i, j int) bool  { return h[i].time.Before(h[j].time) }
+func (h uuidHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *uuidHeap) Push(x interface{}) { *h = append(*h, x.(timedUUID)) }
+
+func (h *uuidHeap) Pop() interface{} {
+	old := *h
+	x := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return x
+}
@@ -0,0 +1,89 @@
+package spade
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func uuidAt(t *testing.T, receivedAt time.Time) string {
+	u, err := NewUUID(receivedAt)
+	require.NoError(t, err)
+	return u.String()
+}
+
+func TestDeduper(t *testing.T) {
+	start := time.Date(2014, 4, 17, 20, 59, 40, 0, time.UTC)
+	d := NewDeduper(time.Minute)
+
+	first := &Event{Uuid: uuidAt(t, start)}
+	second := &Event{Uuid: uuidAt(t, start.Add(30*time.Second))}
+	for _, e := range []*Event{first, second} {
+		dup, err := d.Duplicate(e)
+		require.NoError(t, err)
+		assert.False(t, dup)
+	}
+	for _, e := range []*Event{first, second} {
+		dup, err := d.Duplicate(e)
+		require.NoError(t, err)
+		assert.True(t, dup)
+	}
+	assert.Equal(t, 2, d.Len())
+
+	// moving on forgets first, and a copy of it is let through as too old to tell
+	dup, err := d.Duplicate(&Event{Uuid: uuidAt(t, start.Add(2*time.Minute))})
+	require.NoError(t, err)
+	assert.False(t, dup)
+	assert.Equal(t, 1, d.Len())
+	dup, err = d.Duplicate(first)
+	require.NoError(t, err)
+	assert.False(t, dup)
+	assert.Equal(t, 1, d.Len(), "remembered a UUID older than the window")
+
+	// events arriving a little out of order are still remembered
+	late := &Event{Uuid: uuidAt(t, start.Add(90*time.Second))}
+	dup, err = d.Duplicate(late)
+	require.NoError(t, err)
+	assert.False(t, dup)
+	dup, err = d.Duplicate(late)
+	require.NoError(t, err)
+	assert.True(t, dup)
+
+	_, err = d.Duplicate(&Event{Uuid: testUUID})
+	assert.Error(t, err)
+	_, err = d.Duplicate(&Event{Uuid: "uuid"})
+	assert.Error(t, err)
+}
+
+func TestDeduperConcurrent(t *testing.T) {
+	start := time.Date(2014, 4, 17, 20, 59, 40, 0, time.UTC)
+	events := make([]*Event, 100)
+	for i := range events {
+		events[i] = &Event{Uuid: uuidAt(t, start.Add(time.Duration(i)*time.Millisecond))}
+	}
+
+	d := NewDeduper(time.Hour)
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	unique := 0
+	for w := 0; w < 4; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for _, e := range events {
+				dup, err := d.Duplicate(e)
+				assert.NoError(t, err)
+				if !dup {
+					mu.Lock()
+					unique++
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, len(events), unique)
+}
@@ -2,7 +2,6 @@ package spade
 
 import (
 	"bytes"
-	"compress/flate"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -14,6 +13,9 @@ import (
 // defined in the clients of this code, as it current stands we cannot
 // move one without the other. Recommended ways to solve this sort of
 // thing in Protobuf and Thrift is to have your namespace dicate version
+//
+// Events of older versions are brought up to date by Upgrade as they are
+// decoded.
 const PROTOCOL_VERSION = 4
 const COMPRESSION_VERSION byte = 1
 const INTERNAL_EDGE = "internal"
@@ -30,6 +32,8 @@ type Event struct {
 	EdgeType      string    `json:"edgeType"`
 }
 
+// NewEvent returns an event of the current PROTOCOL_VERSION. New edges should make uuid
+// with NewUUID(receivedAt), so that Deduper can recognize retried events.
 func NewEvent(receivedAt time.Time, clientIp net.IP, xForwardedFor, uuid, data, userAgent string, edgeType string) *Event {
 	return &Event{
 		ReceivedAt:    receivedAt,
@@ -47,19 +51,39 @@ func Marshal(src *Event) ([]byte, error) {
 	return json.Marshal(src)
 }
 
+// Unmarshal decodes an event marshaled by any supported version of this package and
+// upgrades it to PROTOCOL_VERSION.
 func Unmarshal(b []byte, dst *Event) error {
-	return json.Unmarshal(b, &dst)
+	if err := json.Unmarshal(b, &dst); err != nil {
+		return err
+	}
+	return Upgrade(dst)
 }
 
-func Compress(e *Event) ([]byte, error) {
-	data, err := Marshal(e)
+// Compress marshals and compresses an event, with flate unless WithCodec selects
+// another codec, and as JSON unless WithBinaryEncoding is given.
+func Compress(e *Event, opts ...CompressOption) ([]byte, error) {
+	o, codec, err := compressOptionsFor(opts)
+	if err != nil {
+		return nil, err
+	}
+	version := codec.Version
+	marshal := Marshal
+	if o.binary {
+		version |= BINARY_ENCODING
+		marshal = MarshalBinary
+	}
+	data, err := marshal(e)
 	if err != nil {
 		return nil, fmt.Errorf("Unable to marshal spade event: %s", err)
 	}
 
 	var compressed bytes.Buffer
-	compressed.WriteByte(COMPRESSION_VERSION)
-	flator, _ := flate.NewWriter(&compressed, flate.BestCompression)
+	compressed.WriteByte(version)
+	flator, err := codec.NewWriter(&compressed)
+	if err != nil {
+		return nil, fmt.Errorf("Error creating flator: %s", err)
+	}
 	_, err = flator.Write(data)
 	if err != nil {
 		return nil, fmt.Errorf("Error writing to flator: %s", err)
@@ -73,6 +97,8 @@ func Compress(e *Event) ([]byte, error) {
 	return compressed.Bytes(), nil
 }
 
+// Deglob decompresses a glob, with the codec named by its version byte, and returns its
+// events upgraded to PROTOCOL_VERSION.
 func Deglob(glob []byte) (events []*Event, err error) {
 	compressed := bytes.NewBuffer(glob)
 
@@ -80,11 +106,15 @@ func Deglob(glob []byte) (events []*Event, err error) {
 	if err != nil {
 		return nil, fmt.Errorf("error reading version byte: %s", err)
 	}
-	if v != COMPRESSION_VERSION {
-		return nil, fmt.Errorf("unknown version: got %v expected %v", v, COMPRESSION_VERSION)
+	codec, ok := LookupCodec(v)
+	if !ok {
+		return nil, fmt.Errorf("unknown version: %v", v)
 	}
 
-	deflator := flate.NewReader(compressed)
+	deflator, err := codec.NewReader(compressed)
+	if err != nil {
+		return nil, fmt.Errorf("error decompressing: %v", err)
+	}
 	defer func() {
 		if cerr := deflator.Close(); cerr != nil && err == nil {
 			err = fmt.Errorf("error closing glob reader: %v", cerr)
@@ -101,21 +131,39 @@ func Deglob(glob []byte) (events []*Event, err error) {
 	if err != nil {
 		return nil, fmt.Errorf("error unmarshalling: %v", err)
 	}
+	for i, e := range events {
+		if e == nil {
+			continue
+		}
+		if err = Upgrade(e); err != nil {
+			return nil, fmt.Errorf("event %d: %w", i, err)
+		}
+	}
 
 	return
 }
 
+// Decompress decompresses an event made by Compress, with the codec and encoding named by
+// its version byte.
 func Decompress(c []byte) (*Event, error) {
 	compressed := bytes.NewBuffer(c)
 	v, err := compressed.ReadByte()
 	if err != nil {
 		return nil, fmt.Errorf("Error reading version byte: %s", err)
 	}
-	if v != COMPRESSION_VERSION {
-		return nil, fmt.Errorf("Unknown version, got %v expected %v", v, COMPRESSION_VERSION)
+	unmarshal := Unmarshal
+	if v&BINARY_ENCODING != 0 {
+		unmarshal = UnmarshalBinary
+	}
+	codec, ok := LookupCodec(v &^ BINARY_ENCODING)
+	if !ok {
+		return nil, fmt.Errorf("Unknown version %v", v)
 	}
 
-	deflator := flate.NewReader(compressed)
+	deflator, err := codec.NewReader(compressed)
+	if err != nil {
+		return nil, fmt.Errorf("Error decompressing event: %s", err)
+	}
 
 	var decompressed bytes.Buffer
 	_, err = io.Copy(&decompressed, deflator)
@@ -128,9 +176,9 @@ func Decompress(c []byte) (*Event, error) {
 	}
 
 	e := &Event{}
-	err = Unmarshal(decompressed.Bytes(), e)
+	err = unmarshal(decompressed.Bytes(), e)
 	if err != nil {
-		return nil, fmt.Errorf("Error unmarshalling event %s", err)
+		return nil, fmt.Errorf("Error unmarshalling event %w", err)
 	}
 
 	return e, nil
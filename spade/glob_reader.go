@@ -0,0 +1,157 @@
+package spade
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// DefaultGlobReadLimit is the decompressed size limit used when NewGlobReader is given
+// a limit of 0 or less.
+const DefaultGlobReadLimit = 64 << 20
+
+// ErrGlobTooLarge is returned by GlobReader.Next when a glob decompresses to more than
+// the reader's limit.
+var ErrGlobTooLarge = errors.New("glob exceeds decompressed size limit")
+
+// GlobReader reads the events in a glob one at a time, without holding the whole
+// decompressed glob in memory. It is not safe for concurrent use.
+type GlobReader struct {
+	deflator io.ReadCloser
+	decoder  *json.Decoder
+	started  bool
+	err      error
+}
+
+// NewGlobReader returns a GlobReader reading the glob in r, with the codec named by its
+// version byte. Reading more than maxBytes of
+// decompressed data fails with ErrGlobTooLarge.
+func NewGlobReader(r io.Reader, maxBytes int64) (*GlobReader, error) {
+	br := bufio.NewReader(r)
+	v, err := br.ReadByte()
+	if err != nil {
+		return nil, fmt.Errorf("error reading version byte: %s", err)
+	}
+	codec, ok := LookupCodec(v)
+	if !ok {
+		return nil, fmt.Errorf("unknown version: %v", v)
+	}
+
+	if maxBytes <= 0 {
+		maxBytes = DefaultGlobReadLimit
+	}
+	deflator, err := codec.NewReader(br)
+	if err != nil {
+		return nil, fmt.Errorf("error decompressing: %v", err)
+	}
+	return &GlobReader{
+		deflator: deflator,
+		decoder:  json.NewDecoder(&limitReader{r: deflator, remaining: maxBytes}),
+	}, nil
+}
+
+// Next returns the next event in the glob, or io.EOF once there are no more. Null
+// elements, which Deglob returns as nil events, are skipped. Any other error is
+// permanent.
+func (g *GlobReader) Next() (*Event, error) {
+	if g.err != nil {
+		return nil, g.err
+	}
+	e, err := g.next()
+	if err != nil {
+		g.err = err
+	}
+	return e, err
+}
+
+func (g *GlobReader) next() (*Event, error) {
+	if !g.started {
+		g.started = true
+		if err := g.expectDelim('['); err != nil {
+			return nil, err
+		}
+	}
+
+	// null elements are skipped, as Deglob skips them
+	for g.decoder.More() {
+		var e *Event
+		if err := g.decoder.Decode(&e); err != nil {
+			return nil, unmarshalError(err)
+		}
+		if e == nil {
+			continue
+		}
+		if err := Upgrade(e); err != nil {
+			return nil, err
+		}
+		return e, nil
+	}
+
+	if err := g.expectDelim(']'); err != nil {
+		return nil, err
+	}
+	if _, err := g.decoder.Token(); err != io.EOF {
+		if err == nil {
+			err = errors.New("unexpected data after events")
+		}
+		return nil, unmarshalError(err)
+	}
+	return nil, io.EOF
+}
+
+func (g *GlobReader) expectDelim(delim json.Delim) error {
+	tok, err := g.decoder.Token()
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	if err != nil {
+		return unmarshalError(err)
+	}
+	if tok != delim {
+		return fmt.Errorf("error unmarshalling: expected %v, got %v", delim, tok)
+	}
+	return nil
+}
+
+// unmarshalError describes a decoding failure, leaving ErrGlobTooLarge as it is so
+// callers can compare against it.
+func unmarshalError(err error) error {
+	if err == ErrGlobTooLarge {
+		return err
+	}
+	return fmt.Errorf("error unmarshalling: %v", err)
+}
+
+// Close releases the decompressor. It does not close the underlying reader.
+func (g *GlobReader) Close() error {
+	if err := g.deflator.Close(); err != nil {
+		return fmt.Errorf("error closing glob reader: %v", err)
+	}
+	return nil
+}
+
+// limitReader reads from r until remaining runs out, and then fails with ErrGlobTooLarge
+// unless r has also run out.
+type limitReader struct {
+	r         io.Reader
+	remaining int64
+}
+
+func (l *limitReader) Read(p []byte) (int, error) {
+	if l.remaining <= 0 {
+		var probe [1]byte
+		n, err := l.r.Read(probe[:])
+		if n == 0 && err != nil {
+			return 0, err
+		}
+		return 0, ErrGlobTooLarge
+	}
+	if int64(len(p)) > l.remaining {
+		p = p[:l.remaining]
+	}
+	n, err := l.r.Read(p)
+	l.remaining -= int64(n)
+	return n, err
+}
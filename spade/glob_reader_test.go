@@ -0,0 +1,119 @@
+package spade
+
+import (
+	"bytes"
+	"compress/flate"
+	"encoding/json"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func readGlob(t *testing.T, glob []byte, maxBytes int64) ([]*Event, error) {
+	r, err := NewGlobReader(bytes.NewReader(glob), maxBytes)
+	require.NoError(t, err)
+	defer func() { assert.NoError(t, r.Close()) }()
+
+	var events []*Event
+	for {
+		e, err := r.Next()
+		if err == io.EOF {
+			return events, nil
+		}
+		if err != nil {
+			_, again := r.Next()
+			assert.Equal(t, err, again, "error was not permanent")
+			return events, err
+		}
+		events = append(events, e)
+	}
+}
+
+// rawGlob compresses data into a glob without checking that it is valid.
+func rawGlob(t *testing.T, data string) []byte {
+	var b bytes.Buffer
+	b.WriteByte(COMPRESSION_VERSION)
+	flator, _ := flate.NewWriter(&b, flate.BestCompression)
+	_, err := flator.Write([]byte(data))
+	require.NoError(t, err)
+	require.NoError(t, flator.Close())
+	return b.Bytes()
+}
+
+func TestGlobReader(t *testing.T) {
+	for _, n := range []int{0, 1, 25} {
+		events := globEvents(n)
+		read, err := readGlob(t, referenceGlob(t, events), 0)
+		require.NoError(t, err)
+		if n == 0 {
+			assert.Empty(t, read)
+		} else {
+			assert.Equal(t, events, read)
+		}
+	}
+}
+
+func TestGlobReaderNull(t *testing.T) {
+	events := globEvents(2)
+	a, _ := json.Marshal(events[0])
+	b, _ := json.Marshal(events[1])
+	for data, expected := range map[string][]*Event{
+		"[null]":       nil,
+		"[null, null]": nil,
+		"[" + string(a) + ", null, " + string(b) + ", null]": events,
+	} {
+		glob := rawGlob(t, data)
+		read, err := readGlob(t, glob, 0)
+		require.NoError(t, err, data)
+		assert.Equal(t, expected, read, data)
+
+		// Deglob keeps the nulls as nil events
+		deglobbed, err := Deglob(glob)
+		require.NoError(t, err, data)
+		var nonNil []*Event
+		for _, e := range deglobbed {
+			if e != nil {
+				nonNil = append(nonNil, e)
+			}
+		}
+		assert.Equal(t, expected, nonNil, data)
+	}
+}
+
+func TestGlobReaderLimit(t *testing.T) {
+	events := globEvents(10)
+	data, _ := json.Marshal(events)
+	glob := referenceGlob(t, events)
+
+	read, err := readGlob(t, glob, int64(len(data)))
+	require.NoError(t, err, "glob of exactly the limit was rejected")
+	assert.Len(t, read, 10)
+
+	_, err = readGlob(t, glob, int64(len(data)-1))
+	assert.Equal(t, ErrGlobTooLarge, err)
+
+	// a small glob that inflates enormously is stopped early
+	bomb := rawGlob(t, "["+string(bytes.Repeat([]byte(" "), 10<<20))+"]")
+	_, err = readGlob(t, bomb, 1<<20)
+	assert.Equal(t, ErrGlobTooLarge, err)
+}
+
+func TestGlobReaderErrors(t *testing.T) {
+	_, err := NewGlobReader(bytes.NewReader(nil), 0)
+	assert.Error(t, err)
+	_, err = NewGlobReader(bytes.NewReader([]byte{0}), 0)
+	assert.Contains(t, err.Error(), "unknown version")
+
+	for _, data := range []string{
+		``,
+		`{}`,
+		`[{"uuid": "a"}`,
+		`[{"uuid": "a"}, 5]`,
+		`[{"uuid": "a"}] []`,
+	} {
+		_, err := readGlob(t, rawGlob(t, data), 0)
+		assert.Error(t, err, data)
+	}
+}
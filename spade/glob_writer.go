@@ -0,0 +1,157 @@
+package spade
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/twitchscience/scoop_protocol/scoop_protocol"
+)
+
+// ErrGlobFull is returned by GlobWriter.Add when the event would take the glob past its
+// maximum size. The glob should be flushed and the event added to the next one.
+var ErrGlobFull = errors.New("glob is full")
+
+// GlobWriter builds a glob, the format read by Deglob, one event at a time. It is not
+// safe for concurrent use.
+type GlobWriter struct {
+	maxSize int
+	maxAge  time.Duration
+	now     func() time.Time
+
+	codec        Codec
+	compressed   bytes.Buffer
+	flator       io.WriteCloser
+	count        int
+	uncompressed int
+	started      time.Time
+}
+
+// NewGlobWriter returns a GlobWriter whose globs hold at most cfg.MaxSize bytes of
+// uncompressed JSON and are due to be flushed cfg.MaxAge after their first event. cfg
+// must pass Validate. Globs are compressed with flate unless WithCodec selects another
+// codec.
+func NewGlobWriter(cfg scoop_protocol.GlobberConfig, opts ...CompressOption) (*GlobWriter, error) {
+	o, codec, err := compressOptionsFor(opts)
+	if err != nil {
+		return nil, err
+	}
+	if o.binary {
+		return nil, errors.New("globs cannot use binary encoding")
+	}
+	if err = cfg.Validate(); err != nil {
+		return nil, err
+	}
+	maxAge, _ := time.ParseDuration(cfg.MaxAge)
+
+	g := &GlobWriter{maxSize: cfg.MaxSize, maxAge: maxAge, now: time.Now, codec: codec}
+	if err = g.reset(); err != nil {
+		return nil, err
+	}
+	return g, nil
+}
+
+// resetter is implemented by compressors that can be reused for a new stream.
+type resetter interface {
+	Reset(w io.Writer)
+}
+
+// reset starts an empty glob. If no compressor can be made, the glob is left without
+// one for Add to retry.
+func (g *GlobWriter) reset() error {
+	g.count = 0
+	g.uncompressed = 0
+	g.started = time.Time{}
+	g.compressed.Reset()
+	g.compressed.WriteByte(g.codec.Version)
+	if r, ok := g.flator.(resetter); ok {
+		r.Reset(&g.compressed)
+		return nil
+	}
+	flator, err := g.codec.NewWriter(&g.compressed)
+	if err != nil {
+		g.flator = nil
+		return fmt.Errorf("error creating flator: %v", err)
+	}
+	g.flator = flator
+	return nil
+}
+
+// Add appends an event to the glob. It returns ErrGlobFull, and leaves the glob alone, if
+// the glob already has events and the event would take it past its maximum size. An
+// event too big for any glob is accepted into an empty one.
+func (g *GlobWriter) Add(e *Event) error {
+	if g.flator == nil {
+		if err := g.reset(); err != nil {
+			return err
+		}
+	}
+	data, err := Marshal(e)
+	if err != nil {
+		return fmt.Errorf("error marshalling event: %v", err)
+	}
+	// Each event is preceded by '[' or ',' and the glob is closed by ']'.
+	if g.count > 0 && g.uncompressed+len(data)+2 > g.maxSize {
+		return ErrGlobFull
+	}
+
+	sep := byte(',')
+	if g.count == 0 {
+		sep = '['
+		g.started = g.now()
+	}
+	if _, err = g.flator.Write([]byte{sep}); err == nil {
+		_, err = g.flator.Write(data)
+	}
+	if err != nil {
+		return fmt.Errorf("error writing to flator: %v", err)
+	}
+	g.count++
+	g.uncompressed += len(data) + 1
+	return nil
+}
+
+// Len returns the number of events in the glob.
+func (g *GlobWriter) Len() int {
+	return g.count
+}
+
+// UncompressedSize returns the size of the glob's JSON once it is flushed.
+func (g *GlobWriter) UncompressedSize() int {
+	if g.count == 0 {
+		return 0
+	}
+	return g.uncompressed + 1
+}
+
+// CompressedSize returns the number of compressed bytes produced so far, including the
+// version byte. The compressor holds some data back, so the flushed glob is larger.
+func (g *GlobWriter) CompressedSize() int {
+	return g.compressed.Len()
+}
+
+// Expired returns true if the glob's first event was added at least MaxAge ago.
+func (g *GlobWriter) Expired() bool {
+	return g.count > 0 && g.now().Sub(g.started) >= g.maxAge
+}
+
+// Flush returns the finished glob and starts a new, empty one. It returns nil if the
+// glob has no events. If the glob was finished but the new one cannot be started, the
+// glob is returned along with the error and is valid; the next Add starts the new glob.
+func (g *GlobWriter) Flush() ([]byte, error) {
+	if g.count == 0 {
+		return nil, nil
+	}
+	if _, err := g.flator.Write([]byte{']'}); err != nil {
+		return nil, fmt.Errorf("error writing to flator: %v", err)
+	}
+	if err := g.flator.Close(); err != nil {
+		return nil, fmt.Errorf("error closing flator: %v", err)
+	}
+
+	glob := make([]byte, g.compressed.Len())
+	copy(glob, g.compressed.Bytes())
+	return glob, g.reset()
+}
@@ -0,0 +1,172 @@
+package spade
+
+import (
+	"bytes"
+	"compress/flate"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/twitchscience/scoop_protocol/scoop_protocol"
+)
+
+var globConfig = scoop_protocol.GlobberConfig{MaxSize: 990000, MaxAge: "1s", BufferLength: 1024}
+
+func globEvents(n int) []*Event {
+	events := make([]*Event, n)
+	for i := range events {
+		events[i] = NewEvent(time.Unix(1397768380+int64(i), 0).UTC(), net.ParseIP("222.222.222.222"),
+			"192.168.0.1, 222.222.222.222", "uuid", randomString(64), "userAgent", INTERNAL_EDGE)
+	}
+	return events
+}
+
+// referenceGlob compresses all of events at once.
+func referenceGlob(t *testing.T, events []*Event) []byte {
+	data, err := json.Marshal(events)
+	require.NoError(t, err)
+	var b bytes.Buffer
+	b.WriteByte(COMPRESSION_VERSION)
+	flator, _ := flate.NewWriter(&b, flate.BestCompression)
+	_, err = flator.Write(data)
+	require.NoError(t, err)
+	require.NoError(t, flator.Close())
+	return b.Bytes()
+}
+
+func TestGlobWriter(t *testing.T) {
+	g, err := NewGlobWriter(globConfig)
+	require.NoError(t, err)
+
+	glob, err := g.Flush()
+	require.NoError(t, err)
+	assert.Nil(t, glob, "empty glob was flushed")
+
+	// the writer must be reusable after a flush
+	for _, n := range []int{1, 25} {
+		events := globEvents(n)
+		for _, e := range events {
+			require.NoError(t, g.Add(e))
+		}
+		assert.Equal(t, n, g.Len())
+
+		data, _ := json.Marshal(events)
+		assert.Equal(t, len(data), g.UncompressedSize())
+
+		glob, err := g.Flush()
+		require.NoError(t, err)
+		assert.Equal(t, referenceGlob(t, events), glob)
+
+		deglobbed, err := Deglob(glob)
+		require.NoError(t, err)
+		assert.Equal(t, events, deglobbed)
+
+		assert.Equal(t, 0, g.Len())
+		assert.Equal(t, 0, g.UncompressedSize())
+		assert.Equal(t, 1, g.CompressedSize())
+	}
+}
+
+func TestGlobWriterMaxSize(t *testing.T) {
+	events := globEvents(3)
+	one, _ := json.Marshal(events[:1])
+	two, _ := json.Marshal(events[:2])
+
+	cfg := globConfig
+	cfg.MaxSize = len(two)
+	g, err := NewGlobWriter(cfg)
+	require.NoError(t, err)
+	require.NoError(t, g.Add(events[0]))
+	require.NoError(t, g.Add(events[1]))
+	assert.Equal(t, ErrGlobFull, g.Add(events[2]))
+	assert.Equal(t, 2, g.Len(), "rejected event changed the glob")
+
+	glob, err := g.Flush()
+	require.NoError(t, err)
+	deglobbed, err := Deglob(glob)
+	require.NoError(t, err)
+	assert.Equal(t, events[:2], deglobbed)
+
+	// an event bigger than MaxSize still goes out on its own
+	cfg.MaxSize = len(one) - 1
+	g, err = NewGlobWriter(cfg)
+	require.NoError(t, err)
+	require.NoError(t, g.Add(events[0]))
+	assert.Equal(t, ErrGlobFull, g.Add(events[1]))
+}
+
+func TestGlobWriterMaxAge(t *testing.T) {
+	g, err := NewGlobWriter(globConfig)
+	require.NoError(t, err)
+	now := time.Unix(1397768380, 0)
+	g.now = func() time.Time { return now }
+
+	assert.False(t, g.Expired(), "empty glob expired")
+	require.NoError(t, g.Add(globEvents(1)[0]))
+	now = now.Add(999 * time.Millisecond)
+	require.NoError(t, g.Add(globEvents(1)[0]))
+	assert.False(t, g.Expired())
+	now = now.Add(time.Millisecond)
+	assert.True(t, g.Expired())
+
+	_, err = g.Flush()
+	require.NoError(t, err)
+	assert.False(t, g.Expired(), "flushed glob expired")
+}
+
+func TestNewGlobWriterErrors(t *testing.T) {
+	for _, cfg := range []scoop_protocol.GlobberConfig{
+		{MaxSize: 1, MaxAge: "soon", BufferLength: 1},
+		{MaxSize: 1, MaxAge: "0s", BufferLength: 1},
+		{MaxSize: 0, MaxAge: "1s", BufferLength: 1},
+		{MaxSize: 1, MaxAge: "1s", BufferLength: 0},
+	} {
+		_, err := NewGlobWriter(cfg)
+		assert.Error(t, err, "%v", cfg)
+	}
+}
+
+func TestGlobWriterFlushResetError(t *testing.T) {
+	writers := 0
+	codec := Codec{
+		Version: 102,
+		Name:    "once",
+		NewWriter: func(w io.Writer) (io.WriteCloser, error) {
+			if writers++; writers == 2 {
+				return nil, errors.New("no more writers")
+			}
+			return nopWriteCloser{w}, nil
+		},
+		NewReader: func(r io.Reader) (io.ReadCloser, error) {
+			return io.NopCloser(r), nil
+		},
+	}
+	require.NoError(t, RegisterCodec(codec))
+	defer func() {
+		codecsMu.Lock()
+		delete(codecs, codec.Version)
+		codecsMu.Unlock()
+	}()
+
+	g, err := NewGlobWriter(globConfig, WithCodec(codec.Version))
+	require.NoError(t, err)
+	events := globEvents(2)
+	require.NoError(t, g.Add(events[0]))
+	glob, err := g.Flush()
+	assert.Error(t, err)
+	deglobbed, err := Deglob(glob)
+	require.NoError(t, err, "glob returned with the error is not valid")
+	assert.Equal(t, events[:1], deglobbed)
+
+	require.NoError(t, g.Add(events[1]))
+	glob, err = g.Flush()
+	require.NoError(t, err)
+	deglobbed, err = Deglob(glob)
+	require.NoError(t, err)
+	assert.Equal(t, events[1:], deglobbed)
+}
@@ -0,0 +1,76 @@
+package spade
+
+import (
+	"fmt"
+	"sync"
+)
+
+// UnsupportedVersionError is returned when an event's record version is newer than
+// PROTOCOL_VERSION or too old to have an upgrader.
+type UnsupportedVersionError struct {
+	Version int
+}
+
+func (e *UnsupportedVersionError) Error() string {
+	return fmt.Sprintf("unsupported spade event version %d (current version is %d)", e.Version, PROTOCOL_VERSION)
+}
+
+// An Upgrader converts an event of the version it is registered for into an event of
+// the next version. Upgrade takes care of Version.
+type Upgrader func(e *Event) error
+
+// upgraders maps each version older than PROTOCOL_VERSION to the Upgrader that takes
+// its events to the next version. Versions 0 and 1 have none unless one is registered, so
+// their events fail Unmarshal, Decompress and Deglob with an *UnsupportedVersionError
+// instead of decoding.
+var (
+	upgradersMu sync.RWMutex
+	upgraders   = map[int]Upgrader{
+		// Version 3 added UserAgent, which version 2 events leave empty.
+		2: func(e *Event) error { return nil },
+		// Version 4 added EdgeType; every earlier event came from an external edge.
+		3: func(e *Event) error {
+			if e.EdgeType == "" {
+				e.EdgeType = EXTERNAL_EDGE
+			}
+			return nil
+		},
+	}
+)
+
+// RegisterUpgrader sets the upgrader for events of the given version, which must be
+// older than PROTOCOL_VERSION. It fails if the version already has one.
+func RegisterUpgrader(version int, u Upgrader) error {
+	if version >= PROTOCOL_VERSION {
+		return fmt.Errorf("version %d is not older than the current version %d", version, PROTOCOL_VERSION)
+	}
+	upgradersMu.Lock()
+	defer upgradersMu.Unlock()
+	if _, ok := upgraders[version]; ok {
+		return fmt.Errorf("version %d already has an upgrader", version)
+	}
+	upgraders[version] = u
+	return nil
+}
+
+// Upgrade brings an event up to PROTOCOL_VERSION, one version at a time. It returns an
+// *UnsupportedVersionError if any version on the way has no upgrader, and leaves e
+// partially upgraded if an upgrader fails.
+func Upgrade(e *Event) error {
+	if e.Version > PROTOCOL_VERSION {
+		return &UnsupportedVersionError{Version: e.Version}
+	}
+	for e.Version < PROTOCOL_VERSION {
+		upgradersMu.RLock()
+		u, ok := upgraders[e.Version]
+		upgradersMu.RUnlock()
+		if !ok {
+			return &UnsupportedVersionError{Version: e.Version}
+		}
+		if err := u(e); err != nil {
+			return fmt.Errorf("upgrading from version %d: %v", e.Version, err)
+		}
+		e.Version++
+	}
+	return nil
+}
@@ -0,0 +1,104 @@
+package spade
+
+import (
+	"bytes"
+	"compress/flate"
+	"errors"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// compressJSON compresses data the way Compress did for older record versions.
+func compressJSON(t *testing.T, data string) []byte {
+	var b bytes.Buffer
+	b.WriteByte(COMPRESSION_VERSION)
+	flator, _ := flate.NewWriter(&b, flate.BestCompression)
+	_, err := flator.Write([]byte(data))
+	require.NoError(t, err)
+	require.NoError(t, flator.Close())
+	return b.Bytes()
+}
+
+func TestUpgradeArchivedRecords(t *testing.T) {
+	receivedAt := time.Date(2014, 4, 17, 20, 59, 40, 0, time.UTC)
+	for _, tt := range []struct {
+		record   string
+		expected *Event
+	}{
+		{
+			`{"receivedAt":"2014-04-17T20:59:40Z","clientIp":"222.222.222.222","xForwardedFor":"1.1.1.1",` +
+				`"uuid":"a","data":"ZGF0YQ==","recordversion":2}`,
+			&Event{ReceivedAt: receivedAt, ClientIp: net.ParseIP("222.222.222.222"), XForwardedFor: "1.1.1.1",
+				Uuid: "a", Data: "ZGF0YQ==", Version: PROTOCOL_VERSION, EdgeType: EXTERNAL_EDGE},
+		},
+		{
+			`{"receivedAt":"2014-04-17T20:59:40Z","clientIp":"222.222.222.222","xForwardedFor":"",` +
+				`"uuid":"b","data":"ZGF0YQ==","userAgent":"curl","recordversion":3}`,
+			&Event{ReceivedAt: receivedAt, ClientIp: net.ParseIP("222.222.222.222"), Uuid: "b",
+				Data: "ZGF0YQ==", UserAgent: "curl", Version: PROTOCOL_VERSION, EdgeType: EXTERNAL_EDGE},
+		},
+		{
+			`{"receivedAt":"2014-04-17T20:59:40Z","clientIp":"222.222.222.222","xForwardedFor":"",` +
+				`"uuid":"c","data":"ZGF0YQ==","userAgent":"curl","recordversion":4,"edgeType":"internal"}`,
+			&Event{ReceivedAt: receivedAt, ClientIp: net.ParseIP("222.222.222.222"), Uuid: "c",
+				Data: "ZGF0YQ==", UserAgent: "curl", Version: PROTOCOL_VERSION, EdgeType: INTERNAL_EDGE},
+		},
+	} {
+		e, err := Decompress(compressJSON(t, tt.record))
+		require.NoError(t, err, tt.record)
+		assert.Equal(t, tt.expected, e)
+
+		events, err := Deglob(compressJSON(t, "["+tt.record+"]"))
+		require.NoError(t, err, tt.record)
+		assert.Equal(t, []*Event{tt.expected}, events)
+
+		read, err := readGlob(t, compressJSON(t, "["+tt.record+"]"), 0)
+		require.NoError(t, err, tt.record)
+		assert.Equal(t, []*Event{tt.expected}, read)
+	}
+}
+
+func TestUnsupportedVersion(t *testing.T) {
+	for _, version := range []int{0, 1, PROTOCOL_VERSION + 1} {
+		record := fmt.Sprintf(`{"recordversion":%d}`, version)
+		var e Event
+		err := Unmarshal([]byte(record), &e)
+		var unsupported *UnsupportedVersionError
+		require.True(t, errors.As(err, &unsupported), "version %d: %v", version, err)
+		assert.Equal(t, version, unsupported.Version)
+
+		_, err = Decompress(compressJSON(t, record))
+		assert.True(t, errors.As(err, &unsupported), "version %d: %v", version, err)
+		_, err = Deglob(compressJSON(t, "["+record+"]"))
+		assert.True(t, errors.As(err, &unsupported), "version %d: %v", version, err)
+	}
+}
+
+func TestRegisterUpgrader(t *testing.T) {
+	assert.Error(t, RegisterUpgrader(3, func(e *Event) error { return nil }), "replaced an upgrader")
+	assert.Error(t, RegisterUpgrader(PROTOCOL_VERSION, func(e *Event) error { return nil }))
+
+	require.NoError(t, RegisterUpgrader(1, func(e *Event) error {
+		if e.Uuid == "" {
+			return errors.New("no uuid")
+		}
+		e.Data = "upgraded"
+		return nil
+	}))
+	defer func() {
+		upgradersMu.Lock()
+		delete(upgraders, 1)
+		upgradersMu.Unlock()
+	}()
+
+	e := &Event{Version: 1, Uuid: "a"}
+	require.NoError(t, Upgrade(e))
+	assert.Equal(t, &Event{Version: PROTOCOL_VERSION, Uuid: "a", Data: "upgraded", EdgeType: EXTERNAL_EDGE}, e)
+
+	assert.Error(t, Upgrade(&Event{Version: 1}))
+}
@@ -0,0 +1,142 @@
+package spade
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Payload is a single event sent by a client, as found in Event.Data.
+type Payload struct {
+	Event string `json:"event"`
+	// Properties holds the event's properties as decoded by encoding/json, except that
+	// numbers are json.Number so that they keep their exact text.
+	Properties map[string]interface{} `json:"properties"`
+}
+
+// PayloadError is a failure to decode Event.Data.
+type PayloadError struct {
+	// Base64 is true if Data is not valid base64, in which case Offset is into Data.
+	// Otherwise Offset is into the decoded JSON. It is the offset of the byte at which
+	// the problem was found, or of the start of the payload that has the problem.
+	Base64 bool
+	Offset int64
+	Err    error
+}
+
+func (e *PayloadError) Error() string {
+	if e.Base64 {
+		return fmt.Sprintf("invalid base64 at byte %d of data: %v", e.Offset, e.Err)
+	}
+	return fmt.Sprintf("invalid payload at byte %d of decoded data: %v", e.Offset, e.Err)
+}
+
+// DecodePayload decodes Data, which is base64 encoded JSON holding either one payload
+// object or an array of them. The base64 alphabet is found by DetermineBase64Encoding,
+// and padding is optional. Errors are *PayloadError.
+func (e *Event) DecodePayload() ([]Payload, error) {
+	data := []byte(strings.TrimRight(e.Data, "="))
+	enc := DetermineBase64Encoding(data).WithPadding(base64.NoPadding)
+	decoded := make([]byte, enc.DecodedLen(len(data)))
+	n, err := enc.Decode(decoded, data)
+	if err != nil {
+		var offset int64
+		if corrupt, ok := err.(base64.CorruptInputError); ok {
+			offset = int64(corrupt)
+		}
+		return nil, &PayloadError{Base64: true, Offset: offset, Err: err}
+	}
+	return parsePayloads(decoded[:n])
+}
+
+// parsePayloads parses a JSON payload object or array of them.
+func parsePayloads(b []byte) ([]Payload, error) {
+	if valueStart(b, 0) == int64(len(b)) {
+		return nil, &PayloadError{Offset: int64(len(b)), Err: errors.New("no payload")}
+	}
+
+	d := json.NewDecoder(bytes.NewReader(b))
+	d.UseNumber()
+
+	var payloads []Payload
+	if b[valueStart(b, 0)] == '[' {
+		if _, err := d.Token(); err != nil {
+			return nil, jsonError(b, d, err)
+		}
+		for d.More() {
+			p, err := parsePayload(b, d)
+			if err != nil {
+				return nil, err
+			}
+			payloads = append(payloads, p)
+		}
+		if _, err := d.Token(); err != nil {
+			return nil, jsonError(b, d, err)
+		}
+	} else {
+		p, err := parsePayload(b, d)
+		if err != nil {
+			return nil, err
+		}
+		payloads = []Payload{p}
+	}
+
+	offset := valueStart(b, d.InputOffset())
+	if _, err := d.Token(); err != io.EOF {
+		return nil, &PayloadError{Offset: offset, Err: errors.New("unexpected data after payload")}
+	}
+	return payloads, nil
+}
+
+// parsePayload parses the next payload object from d, which is reading b.
+func parsePayload(b []byte, d *json.Decoder) (Payload, error) {
+	offset := valueStart(b, d.InputOffset())
+	var p Payload
+	if err := d.Decode(&p); err != nil {
+		if _, ok := err.(*json.UnmarshalTypeError); ok {
+			return p, &PayloadError{Offset: offset, Err: err}
+		}
+		return p, jsonError(b, d, err)
+	}
+	if p.Event == "" {
+		return p, &PayloadError{Offset: offset, Err: errors.New("payload has no event name")}
+	}
+	if p.Properties == nil {
+		p.Properties = make(map[string]interface{})
+	}
+	return p, nil
+}
+
+// valueStart returns the offset of the first byte at or after offset that is not
+// whitespace or a separator between array elements.
+func valueStart(b []byte, offset int64) int64 {
+	for offset < int64(len(b)) && strings.IndexByte(" \t\r\n,", b[offset]) != -1 {
+		offset++
+	}
+	return offset
+}
+
+// unexpectedEnd is the message of the json.SyntaxError for truncated input.
+const unexpectedEnd = "unexpected end of JSON input"
+
+// jsonError wraps an error from d, which is reading b, in a PayloadError whose offset is
+// that of the byte the error was found at.
+func jsonError(b []byte, d *json.Decoder, err error) error {
+	offset := d.InputOffset()
+	switch e := err.(type) {
+	case *json.SyntaxError:
+		offset = e.Offset - 1
+		if e.Error() == unexpectedEnd {
+			offset = int64(len(b))
+		}
+	default:
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			offset, err = int64(len(b)), io.ErrUnexpectedEOF
+		}
+	}
+	return &PayloadError{Offset: offset, Err: err}
+}
@@ -0,0 +1,101 @@
+package spade
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func eventWithData(data string) *Event {
+	return &Event{Data: data}
+}
+
+func TestDecodePayload(t *testing.T) {
+	single := `{"event":"pageview","properties":{"login":"snoop","count":12345678901234567890,"ok":true}}`
+	expected := []Payload{{
+		Event: "pageview",
+		Properties: map[string]interface{}{
+			"login": "snoop",
+			"count": json.Number("12345678901234567890"),
+			"ok":    true,
+		},
+	}}
+
+	for name, data := range map[string]string{
+		"std":        base64.StdEncoding.EncodeToString([]byte(single)),
+		"url":        base64.URLEncoding.EncodeToString([]byte(single)),
+		"no padding": base64.RawStdEncoding.EncodeToString([]byte(single)),
+		"array":      base64.StdEncoding.EncodeToString([]byte(" [" + single + "] ")),
+	} {
+		payloads, err := eventWithData(data).DecodePayload()
+		require.NoError(t, err, name)
+		assert.Equal(t, expected, payloads, name)
+	}
+}
+
+func TestDecodePayloadAlphabets(t *testing.T) {
+	// These bytes encode to base64 containing both + and /.
+	raw := `{"event":"a","properties":{"v":"ûï¿"}}` + "\n\xfb\xef\xbf"
+	std := base64.StdEncoding.EncodeToString([]byte(raw))
+	require.Contains(t, std, "+")
+
+	for name, data := range map[string]string{
+		"space": strings.Replace(std, "+", " ", -1),
+		"url":   base64.RawURLEncoding.EncodeToString([]byte(raw)),
+	} {
+		_, err := eventWithData(data).DecodePayload()
+		// the trailing garbage proves the whole input was decoded
+		perr, ok := err.(*PayloadError)
+		require.True(t, ok, "%s: %v", name, err)
+		assert.False(t, perr.Base64, name)
+		assert.Equal(t, int64(len(raw)-3), perr.Offset, name)
+	}
+}
+
+func TestDecodeBatch(t *testing.T) {
+	data := base64.StdEncoding.EncodeToString([]byte(`[
+		{"event": "a", "properties": {"x": 1}},
+		{"event": "b"}
+	]`))
+	payloads, err := eventWithData(data).DecodePayload()
+	require.NoError(t, err)
+	assert.Equal(t, []Payload{
+		{Event: "a", Properties: map[string]interface{}{"x": json.Number("1")}},
+		{Event: "b", Properties: map[string]interface{}{}},
+	}, payloads)
+}
+
+func TestDecodePayloadErrors(t *testing.T) {
+	testCases := []struct {
+		name   string
+		json   string
+		offset int64
+	}{
+		{"empty", "  ", 2},
+		{"syntax", `{"event": "a",, }`, 14},
+		{"truncated", `{"event": "a", "properties": {`, 30},
+		{"unterminated array", `[{"event": "a"}`, 15},
+		{"wrong type", `{"event": 5}`, 0},
+		{"not an object", `["pageview"]`, 1},
+		{"no event name", `[{"event": "a"}, {"properties": {}}]`, 17},
+		{"trailing data", `{"event": "a"} {"event": "b"}`, 15},
+	}
+	for _, tc := range testCases {
+		_, err := eventWithData(base64.StdEncoding.EncodeToString([]byte(tc.json))).DecodePayload()
+		perr, ok := err.(*PayloadError)
+		if assert.True(t, ok, "%s: expected a PayloadError, got %v", tc.name, err) {
+			assert.False(t, perr.Base64, tc.name)
+			assert.Equal(t, tc.offset, perr.Offset, "%s: %v", tc.name, perr)
+		}
+	}
+
+	_, err := eventWithData("eyJldmVud!IjoiYSJ9").DecodePayload()
+	perr, ok := err.(*PayloadError)
+	require.True(t, ok, "expected a PayloadError, got %v", err)
+	assert.True(t, perr.Base64)
+	assert.Equal(t, int64(9), perr.Offset)
+}
@@ -0,0 +1,137 @@
+package spade
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+)
+
+// The prefixes RedactionPolicy.TruncateIP keeps of IPv4 and IPv6 addresses.
+const (
+	RedactedIPv4Bits = 24
+	RedactedIPv6Bits = 48
+)
+
+// RedactionPolicy describes how to scrub personal information out of events before
+// they are written somewhere that must not hold it.
+type RedactionPolicy struct {
+	// TruncateIP zeroes the client's address past its first RedactedIPv4Bits or
+	// RedactedIPv6Bits. It implies DropXForwardedFor, since the header holds the full
+	// address.
+	TruncateIP bool
+	// DropXForwardedFor empties XForwardedFor, first replacing ClientIp with the
+	// EffectiveClientIP found with TrustedProxies, so the client's address is kept.
+	DropXForwardedFor bool
+	// TrustedProxies are the proxies whose X-Forwarded-For entries are believed when
+	// XForwardedFor is dropped.
+	TrustedProxies TrustedProxies
+	// HashUserAgent replaces UserAgent with its keyed hash.
+	HashUserAgent bool
+	// RemoveProperties lists payload properties to delete.
+	RemoveProperties []string
+	// HashProperties lists payload properties whose values are replaced with their
+	// keyed hash.
+	HashProperties []string
+	// Key keys the hashes, an HMAC-SHA256 written in hex. The same value hashes the
+	// same way under the same Key, so hashed values can still be joined on.
+	Key []byte
+}
+
+// Validate checks that the policy can be applied.
+func (p *RedactionPolicy) Validate() error {
+	if (p.HashUserAgent || len(p.HashProperties) > 0) && len(p.Key) == 0 {
+		return errors.New("redaction policy hashes values but has no key")
+	}
+	return nil
+}
+
+// RedactEvent applies the policy to an event in place. If the policy touches properties,
+// Data is rewritten as a base64 encoded JSON array of the payloads it held. On error the
+// event is left unchanged.
+func (p *RedactionPolicy) RedactEvent(e *Event) error {
+	if err := p.Validate(); err != nil {
+		return err
+	}
+
+	data := e.Data
+	if len(p.RemoveProperties) > 0 || len(p.HashProperties) > 0 {
+		payloads, err := e.DecodePayload()
+		if err != nil {
+			return err
+		}
+		for _, payload := range payloads {
+			if err = p.RedactProperties(payload.Properties); err != nil {
+				return err
+			}
+		}
+		b, err := json.Marshal(payloads)
+		if err != nil {
+			return fmt.Errorf("error marshalling redacted payloads: %v", err)
+		}
+		data = base64.StdEncoding.EncodeToString(b)
+	}
+
+	e.Data = data
+	if p.DropXForwardedFor || p.TruncateIP {
+		e.ClientIp = e.EffectiveClientIP(p.TrustedProxies)
+		e.XForwardedFor = ""
+	}
+	if p.TruncateIP {
+		e.ClientIp = TruncateIP(e.ClientIp)
+	}
+	if p.HashUserAgent && e.UserAgent != "" {
+		e.UserAgent = p.hash(e.UserAgent)
+	}
+	return nil
+}
+
+// RedactProperties applies the policy's property rules to a payload's properties in
+// place. Values that are not strings are hashed as their JSON text, and nulls are left
+// alone.
+func (p *RedactionPolicy) RedactProperties(properties map[string]interface{}) error {
+	if err := p.Validate(); err != nil {
+		return err
+	}
+	for _, name := range p.RemoveProperties {
+		delete(properties, name)
+	}
+	for _, name := range p.HashProperties {
+		v, ok := properties[name]
+		if !ok || v == nil {
+			continue
+		}
+		s, ok := v.(string)
+		if !ok {
+			b, err := json.Marshal(v)
+			if err != nil {
+				return fmt.Errorf("property %s: %v", name, err)
+			}
+			s = string(b)
+		}
+		properties[name] = p.hash(s)
+	}
+	return nil
+}
+
+func (p *RedactionPolicy) hash(s string) string {
+	mac := hmac.New(sha256.New, p.Key)
+	mac.Write([]byte(s))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// TruncateIP returns ip with everything past its first RedactedIPv4Bits or
+// RedactedIPv6Bits zeroed. IPv4 addresses are returned in their 4-byte form.
+func TruncateIP(ip net.IP) net.IP {
+	if ip == nil {
+		return nil
+	}
+	if ip4 := ip.To4(); ip4 != nil {
+		return ip4.Mask(net.CIDRMask(RedactedIPv4Bits, net.IPv4len*8))
+	}
+	return ip.Mask(net.CIDRMask(RedactedIPv6Bits, net.IPv6len*8))
+}
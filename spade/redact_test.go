@@ -0,0 +1,113 @@
+package spade
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTruncateIP(t *testing.T) {
+	assert.Equal(t, net.IP{81, 2, 69, 0}, TruncateIP(net.ParseIP("81.2.69.160")))
+	assert.Equal(t, net.ParseIP("2001:db8:1234::"), TruncateIP(net.ParseIP("2001:db8:1234:5678::1")))
+	assert.Nil(t, TruncateIP(nil))
+}
+
+func TestRedactProperties(t *testing.T) {
+	p := &RedactionPolicy{
+		RemoveProperties: []string{"email", "missing"},
+		HashProperties:   []string{"login", "user_id", "nothing"},
+		Key:              []byte("key"),
+	}
+	props := map[string]interface{}{
+		"email":   "snoop@example.com",
+		"login":   "snoop",
+		"user_id": json.Number("1234"),
+		"nothing": nil,
+		"channel": "dogg",
+	}
+	require.NoError(t, p.RedactProperties(props))
+
+	// echo -n snoop | openssl dgst -sha256 -hmac key
+	assert.Equal(t, map[string]interface{}{
+		"login":   "3fb56fad843e66cf4c718f50270c7a7f249cf151b819008865eeae78e83aeece",
+		"user_id": p.hash("1234"),
+		"nothing": nil,
+		"channel": "dogg",
+	}, props)
+
+	other := &RedactionPolicy{HashProperties: []string{"login"}, Key: []byte("other key")}
+	otherProps := map[string]interface{}{"login": "snoop"}
+	require.NoError(t, other.RedactProperties(otherProps))
+	assert.NotEqual(t, props["login"], otherProps["login"], "hash did not depend on the key")
+
+	assert.Error(t, (&RedactionPolicy{HashProperties: []string{"login"}}).RedactProperties(props))
+}
+
+func TestRedactEvent(t *testing.T) {
+	data := base64.URLEncoding.EncodeToString([]byte(
+		`{"event": "minute-watched", "properties": {"email": "snoop@example.com", "login": "snoop", "minutes": 2}}`))
+	e := NewEvent(time.Unix(1397768380, 0), net.ParseIP("10.0.0.1"), "81.2.69.160", testUUID, data,
+		"Mozilla/5.0", EXTERNAL_EDGE)
+
+	p := &RedactionPolicy{
+		TruncateIP:        true,
+		DropXForwardedFor: true,
+		TrustedProxies:    DefaultTrustedProxies(),
+		HashUserAgent:     true,
+		RemoveProperties:  []string{"email"},
+		HashProperties:    []string{"login"},
+		Key:               []byte("key"),
+	}
+	require.NoError(t, p.RedactEvent(e))
+
+	assert.Equal(t, net.IP{81, 2, 69, 0}, e.ClientIp, "client address was not kept from X-Forwarded-For")
+	assert.Empty(t, e.XForwardedFor)
+	assert.Equal(t, p.hash("Mozilla/5.0"), e.UserAgent)
+
+	payloads, err := e.DecodePayload()
+	require.NoError(t, err)
+	assert.Equal(t, []Payload{{Event: "minute-watched", Properties: map[string]interface{}{
+		"login":   p.hash("snoop"),
+		"minutes": json.Number("2"),
+	}}}, payloads)
+}
+
+func TestRedactEventLeavesDataAlone(t *testing.T) {
+	e := &Event{ClientIp: net.ParseIP("2001:db8:1234:5678::1"), XForwardedFor: "1.1.1.1", Data: "not base64!"}
+	require.NoError(t, (&RedactionPolicy{TruncateIP: true}).RedactEvent(e))
+	assert.Equal(t, net.ParseIP("2001:db8:1234::"), e.ClientIp)
+	assert.Empty(t, e.XForwardedFor, "truncating the IP kept the full address in X-Forwarded-For")
+	assert.Equal(t, "not base64!", e.Data)
+
+	assert.Error(t, (&RedactionPolicy{RemoveProperties: []string{"email"}}).RedactEvent(e))
+	assert.Error(t, (&RedactionPolicy{HashUserAgent: true}).RedactEvent(e), "hashed without a key")
+}
+
+func TestRedactEventFailureLeavesEventAlone(t *testing.T) {
+	e := &Event{ClientIp: net.ParseIP("10.0.0.1"), XForwardedFor: "81.2.69.160", UserAgent: "Mozilla/5.0",
+		Data: "not base64!"}
+	expected := *e
+	p := &RedactionPolicy{
+		TruncateIP:       true,
+		TrustedProxies:   DefaultTrustedProxies(),
+		HashUserAgent:    true,
+		RemoveProperties: []string{"email"},
+		Key:              []byte("key"),
+	}
+	assert.Error(t, p.RedactEvent(e))
+	assert.Equal(t, expected, *e, "a failed redaction changed the event")
+}
+
+func TestTruncateIPThroughProxy(t *testing.T) {
+	proxies := DefaultTrustedProxies()
+	e := &Event{ClientIp: net.ParseIP("10.0.0.1"), XForwardedFor: "1.1.1.1, 81.2.69.160"}
+	require.NoError(t, (&RedactionPolicy{TruncateIP: true, TrustedProxies: proxies}).RedactEvent(e))
+	assert.Equal(t, net.IP{81, 2, 69, 0}, e.ClientIp)
+	assert.Empty(t, e.XForwardedFor)
+	assert.Equal(t, net.IP{81, 2, 69, 0}, e.EffectiveClientIP(proxies), "the full address was recovered")
+}
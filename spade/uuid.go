@@ -0,0 +1,91 @@
+package spade
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// UUID is a 128-bit identifier in the RFC 4122 layout, as held in Event.Uuid.
+type UUID [16]byte
+
+// ParseUUID parses the canonical textual form of a UUID,
+// xxxxxxxx-xxxx-xxxx-xxxx-xxxxxxxxxxxx, in either case.
+func ParseUUID(s string) (UUID, error) {
+	var u UUID
+	if len(s) != 36 || s[8] != '-' || s[13] != '-' || s[18] != '-' || s[23] != '-' {
+		return u, fmt.Errorf("%q is not a UUID", s)
+	}
+	src := []byte(s[0:8] + s[9:13] + s[14:18] + s[19:23] + s[24:36])
+	if _, err := hex.Decode(u[:], src); err != nil {
+		return u, fmt.Errorf("%q is not a UUID", s)
+	}
+	return u, nil
+}
+
+// String returns the canonical lowercase textual form of the UUID.
+func (u UUID) String() string {
+	var b [36]byte
+	hex.Encode(b[0:8], u[0:4])
+	b[8] = '-'
+	hex.Encode(b[9:13], u[4:6])
+	b[13] = '-'
+	hex.Encode(b[14:18], u[6:8])
+	b[18] = '-'
+	hex.Encode(b[19:23], u[8:10])
+	b[23] = '-'
+	hex.Encode(b[24:36], u[10:16])
+	return string(b[:])
+}
+
+// NewUUID returns a version 7 UUID for an event received at t. The first 48 bits are
+// the milliseconds since the epoch and the next 12 the fraction of the millisecond, so
+// UUIDs sort in the order of t to within 250ns. The rest is random.
+func NewUUID(t time.Time) (UUID, error) {
+	var u UUID
+	ms := t.UnixMilli()
+	if ms < 0 || ms >= 1<<48 {
+		return u, fmt.Errorf("%v cannot be held in a UUID", t)
+	}
+	if _, err := rand.Read(u[6:]); err != nil {
+		return u, fmt.Errorf("error generating UUID: %v", err)
+	}
+	binary.BigEndian.PutUint64(u[0:8], uint64(ms)<<16)
+	frac := uint16((t.UnixNano() - ms*int64(time.Millisecond)) * 4096 / int64(time.Millisecond))
+	binary.BigEndian.PutUint16(u[6:8], 0x7000|frac)
+	u[8] = u[8]&0x3f | 0x80
+	return u, nil
+}
+
+// Version returns the UUID's version, 7 for those made by NewUUID.
+func (u UUID) Version() int {
+	return int(u[6] >> 4)
+}
+
+var errNotTimeOrdered = errors.New("UUID is not version 7")
+
+// Time returns the time a version 7 UUID was made for, to within 250ns.
+func (u UUID) Time() (time.Time, error) {
+	if u.Version() != 7 || u[8]&0xc0 != 0x80 {
+		return time.Time{}, errNotTimeOrdered
+	}
+	ms := int64(binary.BigEndian.Uint64(u[0:8]) >> 16)
+	frac := int64(binary.BigEndian.Uint16(u[6:8]) & 0x0fff)
+	return time.Unix(0, ms*int64(time.Millisecond)+frac*int64(time.Millisecond)/4096), nil
+}
+
+// UUIDTime parses a version 7 UUID and returns the time it was made for.
+func UUIDTime(s string) (time.Time, error) {
+	u, err := ParseUUID(s)
+	if err != nil {
+		return time.Time{}, err
+	}
+	t, err := u.Time()
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%q: %v", s, err)
+	}
+	return t, nil
+}
@@ -0,0 +1,70 @@
+package spade
+
+import (
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseUUID(t *testing.T) {
+	u, err := ParseUUID(testUUID)
+	require.NoError(t, err)
+	assert.Equal(t, testUUID, u.String())
+
+	upper, err := ParseUUID(strings.ToUpper(testUUID))
+	require.NoError(t, err)
+	assert.Equal(t, u, upper)
+
+	for _, s := range []string{
+		"",
+		"uuid",
+		"0b3a3e5c6a4e4c1f9b7e2f1d8c0a4e6b",
+		"0b3a3e5c-6a4e-4c1f-9b7e-2f1d8c0a4e6",
+		"0b3a3e5c-6a4e-4c1f-9b7e_2f1d8c0a4e6b",
+		"0b3a3e5c-6a4e-4c1f-9b7e-2f1d8c0a4e6g",
+		"{b3a3e5c-6a4e-4c1f-9b7e-2f1d8c0a4e6b}",
+	} {
+		_, err := ParseUUID(s)
+		assert.Error(t, err, s)
+	}
+}
+
+func TestNewUUID(t *testing.T) {
+	receivedAt := time.Date(2014, 4, 17, 20, 59, 40, 123456789, time.UTC)
+	u, err := NewUUID(receivedAt)
+	require.NoError(t, err)
+	assert.Equal(t, 7, u.Version())
+	assert.Equal(t, byte(0x80), u[8]&0xc0, "wrong variant")
+	assert.True(t, strings.HasPrefix(u.String(), "0145717c-dedb-774f-"), u.String())
+
+	parsed, err := UUIDTime(u.String())
+	require.NoError(t, err)
+	assert.False(t, parsed.After(receivedAt))
+	assert.True(t, receivedAt.Sub(parsed) < 250*time.Nanosecond, "%v is too far from %v", parsed, receivedAt)
+
+	other, err := NewUUID(receivedAt)
+	require.NoError(t, err)
+	assert.NotEqual(t, u, other)
+
+	_, err = NewUUID(time.Unix(-1, 0))
+	assert.Error(t, err)
+	_, err = UUIDTime(testUUID)
+	assert.Error(t, err, "version 4 UUID has a time")
+	_, err = UUIDTime("uuid")
+	assert.Error(t, err)
+}
+
+func TestUUIDOrder(t *testing.T) {
+	start := time.Date(2014, 4, 17, 20, 59, 40, 0, time.UTC)
+	var uuids []string
+	for i := 0; i < 1000; i++ {
+		u, err := NewUUID(start.Add(time.Duration(i) * 997 * time.Nanosecond))
+		require.NoError(t, err)
+		uuids = append(uuids, u.String())
+	}
+	assert.True(t, sort.StringsAreSorted(uuids), "UUIDs do not sort in time order")
+}
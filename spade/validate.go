@@ -0,0 +1,58 @@
+package spade
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// MaxDataLength is the largest Data, in bytes of base64, that an event may carry.
+const MaxDataLength = 1 << 20
+
+// ValidationErrors holds every problem Event.Validate found with an event.
+type ValidationErrors []error
+
+func (e ValidationErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// Validate checks the event for problems that should stop it from being processed. It
+// returns nil or a ValidationErrors describing all of them. Events should be normalized
+// first, as Validate is strict about EdgeType's case.
+func (e *Event) Validate() error {
+	var errs ValidationErrors
+	if e.ReceivedAt.IsZero() {
+		errs = append(errs, errors.New("receivedAt is not set"))
+	}
+	if _, err := ParseUUID(e.Uuid); err != nil {
+		errs = append(errs, fmt.Errorf("uuid: %v", err))
+	}
+	if e.EdgeType != INTERNAL_EDGE && e.EdgeType != EXTERNAL_EDGE {
+		errs = append(errs, fmt.Errorf("edgeType %q is neither %q nor %q", e.EdgeType, INTERNAL_EDGE, EXTERNAL_EDGE))
+	}
+	if e.Data == "" {
+		errs = append(errs, errors.New("data is empty"))
+	} else if len(e.Data) > MaxDataLength {
+		errs = append(errs, fmt.Errorf("data is %d bytes, more than the limit of %d", len(e.Data), MaxDataLength))
+	}
+
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
+
+// Normalize puts the event's fields in the form Validate and processors expect: it trims
+// whitespace around XForwardedFor, stores an IPv4 ClientIp in its 4-byte form, and
+// lowercases EdgeType.
+func (e *Event) Normalize() {
+	e.XForwardedFor = strings.TrimSpace(e.XForwardedFor)
+	if ip4 := e.ClientIp.To4(); ip4 != nil {
+		e.ClientIp = ip4
+	}
+	e.EdgeType = strings.ToLower(strings.TrimSpace(e.EdgeType))
+}
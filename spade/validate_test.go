@@ -0,0 +1,66 @@
+package spade
+
+import (
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testUUID = "0b3a3e5c-6a4e-4c1f-9b7e-2f1d8c0a4e6b"
+
+func TestValidate(t *testing.T) {
+	valid := func() *Event {
+		return NewEvent(time.Unix(1397768380, 0), net.ParseIP("222.222.222.222"), "", testUUID, "ZGF0YQ==",
+			"userAgent", INTERNAL_EDGE)
+	}
+	assert.NoError(t, valid().Validate())
+
+	tests := []struct {
+		change   func(e *Event)
+		problems int
+	}{
+		{func(e *Event) { e.ReceivedAt = time.Time{} }, 1},
+		{func(e *Event) { e.Uuid = "uuid" }, 1},
+		{func(e *Event) { e.EdgeType = "" }, 1},
+		{func(e *Event) { e.EdgeType = "Internal" }, 1},
+		{func(e *Event) { e.Data = "" }, 1},
+		{func(e *Event) { e.Data = strings.Repeat("A", MaxDataLength+1) }, 1},
+		{func(e *Event) { *e = Event{} }, 4},
+	}
+	for i, tt := range tests {
+		e := valid()
+		tt.change(e)
+		err := e.Validate()
+		require.IsType(t, ValidationErrors{}, err, "test %d", i)
+		assert.Len(t, err.(ValidationErrors), tt.problems, "test %d: %v", i, err)
+	}
+
+	e := valid()
+	e.Data = strings.Repeat("A", MaxDataLength)
+	assert.NoError(t, e.Validate())
+}
+
+func TestNormalize(t *testing.T) {
+	e := NewEvent(time.Unix(1397768380, 0), net.IPv4(222, 222, 222, 222), " 1.1.1.1, 222.222.222.222\n",
+		testUUID, "ZGF0YQ==", "userAgent", " EXTERNAL")
+	require.Len(t, e.ClientIp, net.IPv6len)
+	assert.Error(t, e.Validate())
+
+	e.Normalize()
+	assert.NoError(t, e.Validate())
+	assert.Equal(t, net.IP{222, 222, 222, 222}, e.ClientIp)
+	assert.Equal(t, "1.1.1.1, 222.222.222.222", e.XForwardedFor)
+	assert.Equal(t, EXTERNAL_EDGE, e.EdgeType)
+
+	v6 := &Event{ClientIp: net.ParseIP("2001:db8::1")}
+	v6.Normalize()
+	assert.Equal(t, net.ParseIP("2001:db8::1"), v6.ClientIp)
+
+	empty := &Event{}
+	empty.Normalize()
+	assert.Equal(t, &Event{}, empty)
+}
@@ -0,0 +1,212 @@
+package transformer
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// The conversions below share these rules:
+//
+//   - An empty value converts to NULL (nil), whatever the column type, since clients do
+//     not distinguish an empty property from a missing one.
+//   - Surrounding whitespace is ignored for every type except varchar.
+//   - Numbers may use exponents ("1e3"), as JavaScript clients produce them for large
+//     values, but integer columns reject values with a fractional part.
+
+// DefaultVarcharLength is the length Redshift gives a varchar column declared without one.
+const DefaultVarcharLength = 256
+
+// ConvertBigint converts a value to an int64.
+func ConvertBigint(s string) (interface{}, error) {
+	return convertInteger(s, 64)
+}
+
+// ConvertInt converts a value to an int32.
+func ConvertInt(s string) (interface{}, error) {
+	v, err := convertInteger(s, 32)
+	if v == nil || err != nil {
+		return v, err
+	}
+	return int32(v.(int64)), nil
+}
+
+func convertInteger(s string, bits int) (interface{}, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return nil, nil
+	}
+	i, err := strconv.ParseInt(s, 10, bits)
+	if err == nil {
+		return i, nil
+	}
+	if numErr, ok := err.(*strconv.NumError); ok && numErr.Err == strconv.ErrRange {
+		return nil, fmt.Errorf("%q does not fit in %d bits", s, bits)
+	}
+
+	f, ferr := strconv.ParseFloat(s, 64)
+	if ferr != nil {
+		return nil, fmt.Errorf("%q is not an integer", s)
+	}
+	if f != math.Trunc(f) {
+		return nil, fmt.Errorf("%q has a fractional part", s)
+	}
+	limit := math.Ldexp(1, bits-1)
+	if f < -limit || f >= limit {
+		return nil, fmt.Errorf("%q does not fit in %d bits", s, bits)
+	}
+	return int64(f), nil
+}
+
+// ConvertFloat converts a value to a float64. NaN and infinities are rejected, as
+// Redshift cannot load them.
+func ConvertFloat(s string) (interface{}, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return nil, nil
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return nil, fmt.Errorf("%q is not a number", s)
+	}
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return nil, fmt.Errorf("%q is not a finite number", s)
+	}
+	return f, nil
+}
+
+// ConvertBool converts a value to a bool. It accepts true/false in any case as well as
+// 1/0, t/f and T/F.
+func ConvertBool(s string) (interface{}, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return nil, nil
+	}
+	b, err := strconv.ParseBool(s)
+	if err != nil {
+		b, err = strconv.ParseBool(strings.ToLower(s))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%q is not a boolean", s)
+	}
+	return b, nil
+}
+
+var (
+	pacificOnce sync.Once
+	pacific     *time.Location
+	pacificErr  error
+)
+
+// ConvertTimestamp converts seconds since the epoch, possibly fractional, into a time in
+// America/Los_Angeles. This is what f@timestamp@unix columns have always held, so the
+// stored wall clock shifts with daylight saving time. Precision is kept to the microsecond,
+// which is all a Redshift TIMESTAMP holds.
+func ConvertTimestamp(s string) (interface{}, error) {
+	pacificOnce.Do(func() {
+		pacific, pacificErr = time.LoadLocation("America/Los_Angeles")
+	})
+	if pacificErr != nil {
+		return nil, fmt.Errorf("loading timezone: %v", pacificErr)
+	}
+	return convertUnixTime(s, pacific)
+}
+
+// ConvertTimestampUTC converts seconds since the epoch, possibly fractional, into a time
+// in UTC.
+func ConvertTimestampUTC(s string) (interface{}, error) {
+	return convertUnixTime(s, time.UTC)
+}
+
+func convertUnixTime(s string, loc *time.Location) (interface{}, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return nil, nil
+	}
+	sec, usec, err := parseUnixSeconds(s)
+	if err != nil {
+		return nil, err
+	}
+	return time.Unix(sec, usec*int64(time.Microsecond)).In(loc), nil
+}
+
+// parseUnixSeconds splits a number of seconds into whole seconds and microseconds. Plain
+// decimals are parsed exactly, dropping digits past the microsecond; anything else goes
+// through a float64.
+func parseUnixSeconds(s string) (sec int64, usec int64, err error) {
+	if !strings.ContainsAny(s, "eE") {
+		whole, frac := s, ""
+		if i := strings.IndexByte(s, '.'); i != -1 {
+			whole, frac = s[:i], s[i+1:]
+		}
+		sec, err = strconv.ParseInt(whole, 10, 64)
+		if err == nil && strings.Trim(frac, "0123456789") == "" {
+			usec, _ = strconv.ParseInt((frac + "000000")[:6], 10, 64)
+			if strings.HasPrefix(whole, "-") {
+				usec = -usec
+			}
+			return sec, usec, nil
+		}
+	}
+
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil || math.IsNaN(f) || math.IsInf(f, 0) || math.Abs(f) > math.MaxInt64/1e6 {
+		return 0, 0, fmt.Errorf("%q is not a unix timestamp", s)
+	}
+	usec = int64(math.Round(f * 1e6))
+	return usec / 1e6, usec % 1e6, nil
+}
+
+// NewVarcharConverter returns a conversion for a varchar column of the given length in
+// bytes. Longer values are truncated to fit without splitting a UTF-8 sequence, and invalid
+// UTF-8 is replaced with U+FFFD since Redshift refuses to load it.
+func NewVarcharConverter(length int) (ConvertFunc, error) {
+	if length < 1 || length > MaxVarcharLength {
+		return nil, fmt.Errorf("invalid varchar length %d", length)
+	}
+	return func(s string) (interface{}, error) {
+		if s == "" {
+			return nil, nil
+		}
+		return truncateUTF8(strings.ToValidUTF8(s, string(utf8.RuneError)), length), nil
+	}, nil
+}
+
+// truncateUTF8 returns the longest prefix of s that is at most n bytes and does not end
+// in the middle of a rune.
+func truncateUTF8(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
+
+// ConverterFor returns the conversion for a column of this transformer created with the
+// given options. It differs from Convert for transformers that take a length.
+func (t Transformer) ConverterFor(options string) (ConvertFunc, error) {
+	opts, err := ParseColumnOptions(options)
+	if err != nil {
+		return nil, err
+	}
+	if t.Sized == nil {
+		if opts.Length != 0 {
+			return nil, fmt.Errorf("%s column cannot have a length", t.Name)
+		}
+		if t.Convert == nil {
+			return nil, fmt.Errorf("%s has no conversion for a lone value", t.Name)
+		}
+		return t.Convert, nil
+	}
+	if opts.Length == 0 {
+		return nil, errors.New(t.Name + " column needs a length")
+	}
+	return t.Sized(opts.Length)
+}
@@ -0,0 +1,184 @@
+package transformer
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type convertCase struct {
+	in       string
+	expected interface{}
+}
+
+func checkConvert(t *testing.T, name string, convert ConvertFunc, valid []convertCase, invalid []string) {
+	for _, tc := range valid {
+		v, err := convert(tc.in)
+		if assert.NoError(t, err, "%s(%q)", name, tc.in) {
+			assert.Equal(t, tc.expected, v, "%s(%q)", name, tc.in)
+		}
+	}
+	for _, in := range invalid {
+		_, err := convert(in)
+		assert.Error(t, err, "%s(%q)", name, in)
+	}
+}
+
+func TestConvertBigint(t *testing.T) {
+	checkConvert(t, "bigint", ConvertBigint, []convertCase{
+		{"", nil},
+		{"42", int64(42)},
+		{" -42 ", int64(-42)},
+		{"+7", int64(7)},
+		{"9223372036854775807", int64(9223372036854775807)},
+		{"1e3", int64(1000)},
+		{"1.5E+2", int64(150)},
+		{"42.0", int64(42)},
+		{"-0", int64(0)},
+	}, []string{
+		"forty-two",
+		"1.5",
+		"9223372036854775808",
+		"9.3e18",
+		"0x10",
+		"NaN",
+	})
+}
+
+func TestConvertInt(t *testing.T) {
+	checkConvert(t, "int", ConvertInt, []convertCase{
+		{"", nil},
+		{"42", int32(42)},
+		{"2147483647", int32(2147483647)},
+		{"-2147483648", int32(-2147483648)},
+		{"2e9", int32(2000000000)},
+	}, []string{
+		"2147483648",
+		"3e9",
+		"1.25",
+	})
+}
+
+func TestConvertFloat(t *testing.T) {
+	checkConvert(t, "float", ConvertFloat, []convertCase{
+		{"", nil},
+		{"1.5", 1.5},
+		{" 2 ", 2.0},
+		{"-1e-3", -0.001},
+		{"6.02E23", 6.02e23},
+	}, []string{
+		"one",
+		"NaN",
+		"Inf",
+		"-Infinity",
+		"1e400",
+	})
+}
+
+func TestConvertBool(t *testing.T) {
+	checkConvert(t, "bool", ConvertBool, []convertCase{
+		{"", nil},
+		{"true", true},
+		{"False", false},
+		{"TRUE", true},
+		{"tRuE", true},
+		{"1", true},
+		{"0", false},
+		{" f ", false},
+	}, []string{
+		"yes",
+		"2",
+		"null",
+	})
+}
+
+func TestConvertTimestamp(t *testing.T) {
+	pacific, err := time.LoadLocation("America/Los_Angeles")
+	require.NoError(t, err)
+
+	// 2014-03-09 is when Pacific time switched to daylight saving time.
+	checkConvert(t, "f@timestamp@unix", ConvertTimestamp, []convertCase{
+		{"", nil},
+		{"1394359199", time.Date(2014, 3, 9, 1, 59, 59, 0, pacific)},
+		{"1394359200", time.Date(2014, 3, 9, 3, 0, 0, 0, pacific)},
+		{"1394359200.25", time.Date(2014, 3, 9, 3, 0, 0, 250000000, pacific)},
+	}, []string{
+		"yesterday",
+		"2014-03-09T03:00:00Z",
+	})
+
+	v, err := ConvertTimestamp("1394359200")
+	require.NoError(t, err)
+	assert.Equal(t, "PDT", v.(time.Time).Format("MST"))
+}
+
+func TestConvertTimestampUTC(t *testing.T) {
+	checkConvert(t, "f@timestamp@unix-utc", ConvertTimestampUTC, []convertCase{
+		{"", nil},
+		{"0", time.Unix(0, 0).UTC()},
+		{"1397768380", time.Date(2014, 4, 17, 20, 59, 40, 0, time.UTC)},
+		{" 1397768380.123456789 ", time.Date(2014, 4, 17, 20, 59, 40, 123456000, time.UTC)},
+		{"1397768380.5", time.Date(2014, 4, 17, 20, 59, 40, 500000000, time.UTC)},
+		{"1.39776838e9", time.Date(2014, 4, 17, 20, 59, 40, 0, time.UTC)},
+		{"-0.5", time.Date(1969, 12, 31, 23, 59, 59, 500000000, time.UTC)},
+		{".5", time.Date(1970, 1, 1, 0, 0, 0, 500000000, time.UTC)},
+	}, []string{
+		"now",
+		"1397768380.12a",
+		"1e300",
+		"NaN",
+	})
+}
+
+func TestVarcharConverter(t *testing.T) {
+	convert, err := NewVarcharConverter(4)
+	require.NoError(t, err)
+	checkConvert(t, "varchar(4)", convert, []convertCase{
+		{"", nil},
+		{"abc", "abc"},
+		{"abcd", "abcd"},
+		{"abcde", "abcd"},
+		{" ab ", " ab "},
+		{"aé", "aé"},
+		{"abcé", "abc"},  // é is two bytes and does not fit
+		{"a日本", "a日"},    // each character is three bytes
+		{"a\xffc", "a�"}, // invalid UTF-8 is replaced, then truncated
+	}, nil)
+
+	_, err = NewVarcharConverter(0)
+	assert.Error(t, err)
+	_, err = NewVarcharConverter(MaxVarcharLength + 1)
+	assert.Error(t, err)
+}
+
+func TestConverterFor(t *testing.T) {
+	varchar, _ := Lookup("varchar")
+	convert, err := varchar.ConverterFor("(3) sortkey")
+	require.NoError(t, err)
+	v, err := convert("abcdef")
+	require.NoError(t, err)
+	assert.Equal(t, "abc", v)
+
+	v, err = varchar.Convert(strings.Repeat("x", 300))
+	require.NoError(t, err)
+	assert.Len(t, v, DefaultVarcharLength)
+
+	_, err = varchar.ConverterFor("")
+	assert.Error(t, err, "varchar without length worked")
+
+	bigint, _ := Lookup("bigint")
+	_, err = bigint.ConverterFor("(3)")
+	assert.Error(t, err, "bigint with length worked")
+	convert, err = bigint.ConverterFor(" distkey")
+	require.NoError(t, err)
+	v, err = convert("12")
+	require.NoError(t, err)
+	assert.Equal(t, int64(12), v)
+
+	city, _ := Lookup("ipCity")
+	_, err = city.ConverterFor("")
+	assert.Error(t, err, "ip transformer converted a lone value")
+}
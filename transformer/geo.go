@@ -0,0 +1,96 @@
+package transformer
+
+import (
+	"fmt"
+	"net"
+)
+
+// GeoRecord is what is known about where an IP address is.
+type GeoRecord struct {
+	City            string
+	Region          string
+	Country         string // ISO 3166-1 alpha-2 code
+	ASN             uint32 // autonomous system number, 0 if unknown
+	ASNOrganization string
+}
+
+// GeoLookup finds the location of IP addresses for the ip transformers.
+type GeoLookup interface {
+	// Lookup returns the record for ip, or nil if nothing is known about it.
+	Lookup(ip net.IP) (*GeoRecord, error)
+}
+
+// geoFields extracts the value of each ip transformer from a GeoRecord. A nil return
+// stands for NULL.
+var geoFields = map[string]func(*GeoRecord) interface{}{
+	"ipCity":    func(r *GeoRecord) interface{} { return nonEmpty(r.City) },
+	"ipRegion":  func(r *GeoRecord) interface{} { return nonEmpty(r.Region) },
+	"ipCountry": func(r *GeoRecord) interface{} { return nonEmpty(r.Country) },
+	"ipAsn": func(r *GeoRecord) interface{} {
+		if r.ASN == 0 {
+			return nil
+		}
+		if r.ASNOrganization == "" {
+			return fmt.Sprintf("AS%d", r.ASN)
+		}
+		return fmt.Sprintf("AS%d %s", r.ASN, r.ASNOrganization)
+	},
+	// ASNs are 32-bit unsigned, so they do not all fit in an INT column.
+	"ipAsnInteger": func(r *GeoRecord) interface{} {
+		if r.ASN == 0 {
+			return nil
+		}
+		return int64(r.ASN)
+	},
+}
+
+func nonEmpty(s string) interface{} {
+	if s == "" {
+		return nil
+	}
+	return s
+}
+
+// UseGeoLookup makes the registry's ip transformers convert IP addresses with lookup.
+// Addresses that are empty or unknown to lookup convert to NULL.
+func (r *Registry) UseGeoLookup(lookup GeoLookup) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for name, field := range geoFields {
+		t, ok := r.transformers[name]
+		if !ok {
+			continue
+		}
+		field := field
+		t.Convert = func(s string) (interface{}, error) {
+			if s == "" {
+				return nil, nil
+			}
+			ip := net.ParseIP(s)
+			if ip == nil {
+				return nil, fmt.Errorf("%q is not an IP address", s)
+			}
+			record, err := lookup.Lookup(ip)
+			if err != nil || record == nil {
+				return nil, err
+			}
+			return field(record), nil
+		}
+		r.transformers[name] = t
+	}
+}
+
+// ConvertIP converts an IP address, such as spade.Event.ClientIp, with an ip transformer.
+// A nil address converts to NULL.
+func (t Transformer) ConvertIP(ip net.IP) (interface{}, error) {
+	if !t.IPLookup {
+		return nil, fmt.Errorf("%s does not convert IP addresses", t.Name)
+	}
+	if t.Convert == nil {
+		return nil, fmt.Errorf("%s has no GeoLookup", t.Name)
+	}
+	if ip == nil {
+		return nil, nil
+	}
+	return t.Convert(ip.String())
+}
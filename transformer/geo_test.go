@@ -0,0 +1,76 @@
+package transformer
+
+import (
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type mapGeoLookup map[string]*GeoRecord
+
+func (m mapGeoLookup) Lookup(ip net.IP) (*GeoRecord, error) {
+	if ip.Equal(net.IPv4(10, 0, 0, 1)) {
+		return nil, errors.New("lookup failed")
+	}
+	return m[ip.String()], nil
+}
+
+func TestUseGeoLookup(t *testing.T) {
+	r := NewRegistry()
+	r.UseGeoLookup(mapGeoLookup{
+		"81.2.69.160": {City: "London", Region: "England", Country: "GB", ASN: 20712, ASNOrganization: "Andrews & Arnold Ltd"},
+		"1.128.0.1":   {ASN: 1221},
+		"1.128.0.2":   {ASN: 4200000000},
+	})
+
+	expected := map[string][]interface{}{
+		// 81.2.69.160, 1.128.0.1, 8.8.8.8
+		"ipCity":       {"London", nil, nil},
+		"ipRegion":     {"England", nil, nil},
+		"ipCountry":    {"GB", nil, nil},
+		"ipAsn":        {"AS20712 Andrews & Arnold Ltd", "AS1221", nil},
+		"ipAsnInteger": {int64(20712), int64(1221), nil},
+	}
+	for name, values := range expected {
+		tr, ok := r.Lookup(name)
+		require.True(t, ok)
+		for i, ip := range []string{"81.2.69.160", "1.128.0.1", "8.8.8.8"} {
+			v, err := tr.Convert(ip)
+			assert.NoError(t, err)
+			assert.Equal(t, values[i], v, "%s(%s)", name, ip)
+
+			v, err = tr.ConvertIP(net.ParseIP(ip))
+			assert.NoError(t, err)
+			assert.Equal(t, values[i], v, "%s(%s)", name, ip)
+		}
+
+		v, err := tr.Convert("")
+		assert.NoError(t, err)
+		assert.Nil(t, v)
+		v, err = tr.ConvertIP(nil)
+		assert.NoError(t, err)
+		assert.Nil(t, v)
+
+		_, err = tr.Convert("not an ip")
+		assert.Error(t, err)
+		_, err = tr.Convert("10.0.0.1")
+		assert.Error(t, err)
+	}
+
+	asn, _ := r.Lookup("ipAsnInteger")
+	v, err := asn.Convert("1.128.0.2")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(4200000000), v, "a 32-bit ASN wrapped")
+
+	city, _ := Lookup("ipCity")
+	assert.Nil(t, city.Convert, "binding one registry changed the default one")
+	_, err = city.ConvertIP(net.IPv4(8, 8, 8, 8))
+	assert.Error(t, err)
+
+	bigint, _ := Lookup("bigint")
+	_, err = bigint.ConvertIP(net.IPv4(8, 8, 8, 8))
+	assert.Error(t, err)
+}
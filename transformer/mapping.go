@@ -0,0 +1,148 @@
+package transformer
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// MappingSource maps values of a supporting column to user IDs for userIDWithMapping.
+type MappingSource interface {
+	// Lookup returns the user ID that value of the given column maps to, and false if
+	// there is none.
+	Lookup(column, value string) (int64, bool, error)
+}
+
+// MemoryMappingSource is a MappingSource held in memory, keyed by column and then value.
+type MemoryMappingSource map[string]map[string]int64
+
+// Lookup implements MappingSource.
+func (m MemoryMappingSource) Lookup(column, value string) (int64, bool, error) {
+	id, ok := m[column][value]
+	return id, ok, nil
+}
+
+// FileMappingSource is a MappingSource read from a file with one mapping per line, made of
+// the column, the value and the user ID separated by tabs. Surrounding whitespace, including
+// the CR of CRLF line endings, is ignored in each field, as Resolve ignores it in
+// properties. Blank lines and lines starting with # are ignored. It is safe for concurrent
+// use.
+type FileMappingSource struct {
+	path string
+
+	mu       sync.RWMutex
+	mappings MemoryMappingSource
+}
+
+// NewFileMappingSource loads the mappings in the file at path.
+func NewFileMappingSource(path string) (*FileMappingSource, error) {
+	s := &FileMappingSource{path: path}
+	if err := s.Reload(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Reload reads the file again. On error the previous mappings stay in use.
+func (s *FileMappingSource) Reload() error {
+	f, err := os.Open(s.path)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = f.Close() }()
+
+	mappings := make(MemoryMappingSource)
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		text := scanner.Text()
+		if strings.TrimSpace(text) == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		fields := strings.Split(text, "\t")
+		if len(fields) != 3 {
+			return fmt.Errorf("%s:%d: expected 3 fields, got %d", s.path, line, len(fields))
+		}
+		for i := range fields {
+			fields[i] = strings.TrimSpace(fields[i])
+		}
+		id, err := strconv.ParseInt(fields[2], 10, 64)
+		if err != nil {
+			return fmt.Errorf("%s:%d: invalid user ID %q", s.path, line, fields[2])
+		}
+		if mappings[fields[0]] == nil {
+			mappings[fields[0]] = make(map[string]int64)
+		}
+		mappings[fields[0]][fields[1]] = id
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("reading %s: %v", s.path, err)
+	}
+
+	s.mu.Lock()
+	s.mappings = mappings
+	s.mu.Unlock()
+	return nil
+}
+
+// Lookup implements MappingSource.
+func (s *FileMappingSource) Lookup(column, value string) (int64, bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.mappings.Lookup(column, value)
+}
+
+// Resolution is the outcome of resolving a userIDWithMapping column.
+type Resolution struct {
+	// Value is the user ID as an int64, or nil for NULL.
+	Value interface{}
+	// Column is the property that produced Value: the column's inbound property or one
+	// of its supporting columns. It is empty if Value is nil.
+	Column string
+}
+
+// UserIDMapper resolves userIDWithMapping columns.
+type UserIDMapper struct {
+	Source MappingSource
+}
+
+// NewUserIDMapper returns a UserIDMapper that looks values up in source.
+func NewUserIDMapper(source MappingSource) *UserIDMapper {
+	return &UserIDMapper{Source: source}
+}
+
+// Resolve returns the user ID for a column with the given inbound property and comma
+// separated supporting columns. A non-empty inbound property is used as the ID directly.
+// Otherwise the supporting columns are tried in order, and the first one with a
+// non-empty property that the source knows about provides the ID. Surrounding whitespace
+// is ignored in every property.
+func (m *UserIDMapper) Resolve(inbound, supporting string, properties map[string]string) (Resolution, error) {
+	if value := strings.TrimSpace(properties[inbound]); value != "" {
+		id, err := ConvertBigint(value)
+		if err != nil {
+			return Resolution{}, fmt.Errorf("%s: %v", inbound, err)
+		}
+		return Resolution{Value: id, Column: inbound}, nil
+	}
+
+	if supporting == "" {
+		return Resolution{}, nil
+	}
+	for _, column := range strings.Split(supporting, ",") {
+		column = strings.TrimSpace(column)
+		value := strings.TrimSpace(properties[column])
+		if value == "" {
+			continue
+		}
+		id, ok, err := m.Source.Lookup(column, value)
+		if err != nil {
+			return Resolution{}, fmt.Errorf("mapping %s: %v", column, err)
+		}
+		if ok {
+			return Resolution{Value: id, Column: column}, nil
+		}
+	}
+	return Resolution{}, nil
+}
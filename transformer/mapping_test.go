@@ -0,0 +1,106 @@
+package transformer
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type failingSource struct{}
+
+func (failingSource) Lookup(column, value string) (int64, bool, error) {
+	return 0, false, errors.New("source unavailable")
+}
+
+func TestResolve(t *testing.T) {
+	source, err := NewFileMappingSource("testdata/mappings.tsv")
+	require.NoError(t, err)
+
+	for name, source := range map[string]MappingSource{
+		"file": source,
+		"memory": MemoryMappingSource{
+			"login":   {"snoop": 1234, "snoop_dogg": 5678},
+			"channel": {"snoopdogg": 1234},
+		},
+	} {
+		m := NewUserIDMapper(source)
+		testCases := []struct {
+			properties map[string]string
+			expected   Resolution
+		}{
+			{map[string]string{"user_id": "42", "login": "snoop"}, Resolution{int64(42), "user_id"}},
+			{map[string]string{"login": "snoop"}, Resolution{int64(1234), "login"}},
+			{map[string]string{"user_id": " 42 "}, Resolution{int64(42), "user_id"}},
+			{map[string]string{"user_id": "  ", "login": " snoop\t"}, Resolution{int64(1234), "login"}},
+			{map[string]string{"user_id": "", "login": "snoop_dogg", "channel": "snoopdogg"}, Resolution{int64(5678), "login"}},
+			{map[string]string{"login": "unknown", "channel": "snoopdogg"}, Resolution{int64(1234), "channel"}},
+			{map[string]string{"login": "", "channel": "snoopdogg"}, Resolution{int64(1234), "channel"}},
+			{map[string]string{"login": "unknown"}, Resolution{}},
+			{map[string]string{}, Resolution{}},
+		}
+		for _, tc := range testCases {
+			r, err := m.Resolve("user_id", "login, channel", tc.properties)
+			assert.NoError(t, err, "%s %v", name, tc.properties)
+			assert.Equal(t, tc.expected, r, "%s %v", name, tc.properties)
+		}
+
+		r, err := m.Resolve("user_id", "", map[string]string{"login": "snoop"})
+		assert.NoError(t, err)
+		assert.Equal(t, Resolution{}, r)
+
+		_, err = m.Resolve("user_id", "login", map[string]string{"user_id": "snoop"})
+		assert.Error(t, err, "non numeric user ID worked")
+	}
+
+	_, err = NewUserIDMapper(failingSource{}).Resolve("user_id", "login", map[string]string{"login": "snoop"})
+	assert.Error(t, err)
+}
+
+func TestFileMappingSource(t *testing.T) {
+	dir, err := ioutil.TempDir("", "mapping")
+	require.NoError(t, err)
+	defer func() { _ = os.RemoveAll(dir) }()
+	path := filepath.Join(dir, "mappings.tsv")
+
+	require.NoError(t, ioutil.WriteFile(path, []byte("login\tsnoop\t1\n"), 0644))
+	s, err := NewFileMappingSource(path)
+	require.NoError(t, err)
+	id, ok, err := s.Lookup("login", "snoop")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, int64(1), id)
+
+	require.NoError(t, ioutil.WriteFile(path, []byte("login\tsnoop\t2\n"), 0644))
+	require.NoError(t, s.Reload())
+	id, _, _ = s.Lookup("login", "snoop")
+	assert.Equal(t, int64(2), id)
+
+	for _, bad := range []string{"login\tsnoop\n", "login\tsnoop\tabc\n"} {
+		require.NoError(t, ioutil.WriteFile(path, []byte(bad), 0644))
+		assert.Error(t, s.Reload(), bad)
+		id, _, _ = s.Lookup("login", "snoop")
+		assert.Equal(t, int64(2), id, "failed reload dropped the old mappings")
+	}
+
+	_, err = NewFileMappingSource(filepath.Join(dir, "missing.tsv"))
+	assert.Error(t, err)
+}
+
+func TestFileMappingSourceCRLF(t *testing.T) {
+	// mappings_crlf.tsv has CRLF line endings and fields padded with spaces
+	s, err := NewFileMappingSource("testdata/mappings_crlf.tsv")
+	require.NoError(t, err)
+	assert.Equal(t, MemoryMappingSource{
+		"login":   {"snoop": 1234, "snoop_dogg": 5678},
+		"channel": {"snoopdogg": 1234},
+	}, s.mappings)
+
+	r, err := NewUserIDMapper(s).Resolve("user_id", "login", map[string]string{"login": " snoop "})
+	require.NoError(t, err)
+	assert.Equal(t, Resolution{int64(1234), "login"}, r)
+}
@@ -0,0 +1,87 @@
+package transformer
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// MaxVarcharLength is the largest length Redshift allows for a varchar column.
+const MaxVarcharLength = 65535
+
+// ColumnOptions is the parsed form of a column's creation options, e.g. "(255) sortkey".
+type ColumnOptions struct {
+	Length   int    // length of the column in bytes, 0 if not given
+	SortKey  bool   // the column is the table's sort key
+	DistKey  bool   // the column is the table's distribution key
+	NotNull  bool   // the column may not hold NULL
+	Encoding string // the column's compression encoding, empty for the default
+}
+
+var validEncodings = map[string]bool{
+	"raw":       true,
+	"az64":      true,
+	"bytedict":  true,
+	"delta":     true,
+	"delta32k":  true,
+	"lzo":       true,
+	"mostly8":   true,
+	"mostly16":  true,
+	"mostly32":  true,
+	"runlength": true,
+	"text255":   true,
+	"text32k":   true,
+	"zstd":      true,
+}
+
+// ParseColumnOptions parses column creation options. An optional length in parentheses
+// comes first, followed by any of the keywords sortkey, distkey, "not null" and
+// "encode <encoding>", case insensitively.
+func ParseColumnOptions(options string) (ColumnOptions, error) {
+	var opts ColumnOptions
+	rest := strings.TrimSpace(options)
+
+	if strings.HasPrefix(rest, "(") {
+		end := strings.Index(rest, ")")
+		if end == -1 {
+			return opts, fmt.Errorf("unterminated length in options %q", options)
+		}
+		length, err := strconv.Atoi(strings.TrimSpace(rest[1:end]))
+		if err != nil || length < 1 || length > MaxVarcharLength {
+			return opts, fmt.Errorf("invalid length %q in options %q", rest[1:end], options)
+		}
+		opts.Length = length
+		rest = rest[end+1:]
+	}
+
+	fields := strings.Fields(strings.ToLower(rest))
+	for i := 0; i < len(fields); i++ {
+		switch fields[i] {
+		case "sortkey":
+			if opts.SortKey {
+				return opts, fmt.Errorf("sortkey repeated in options %q", options)
+			}
+			opts.SortKey = true
+		case "distkey":
+			if opts.DistKey {
+				return opts, fmt.Errorf("distkey repeated in options %q", options)
+			}
+			opts.DistKey = true
+		case "not":
+			if i+1 == len(fields) || fields[i+1] != "null" || opts.NotNull {
+				return opts, fmt.Errorf("invalid not null in options %q", options)
+			}
+			opts.NotNull = true
+			i++
+		case "encode":
+			if i+1 == len(fields) || !validEncodings[fields[i+1]] || opts.Encoding != "" {
+				return opts, fmt.Errorf("invalid encoding in options %q", options)
+			}
+			opts.Encoding = fields[i+1]
+			i++
+		default:
+			return opts, fmt.Errorf("unknown option %q in options %q", fields[i], options)
+		}
+	}
+	return opts, nil
+}
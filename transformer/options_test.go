@@ -0,0 +1,46 @@
+package transformer
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseColumnOptions(t *testing.T) {
+	testCases := []struct {
+		options  string
+		expected ColumnOptions
+	}{
+		{"", ColumnOptions{}},
+		{"(255)", ColumnOptions{Length: 255}},
+		{" (32) ", ColumnOptions{Length: 32}},
+		{" sortkey", ColumnOptions{SortKey: true}},
+		{"(64) distkey", ColumnOptions{Length: 64, DistKey: true}},
+		{"SORTKEY NOT NULL encode zstd", ColumnOptions{SortKey: true, NotNull: true, Encoding: "zstd"}},
+		{"(65535)", ColumnOptions{Length: MaxVarcharLength}},
+	}
+	for _, tc := range testCases {
+		opts, err := ParseColumnOptions(tc.options)
+		assert.NoError(t, err, tc.options)
+		assert.Equal(t, tc.expected, opts, tc.options)
+	}
+}
+
+func TestParseInvalidColumnOptions(t *testing.T) {
+	for _, options := range []string{
+		"(",
+		"(abc)",
+		"(0)",
+		"(65536)",
+		"sortkey (32)",
+		"sortkey sortkey",
+		"not",
+		"not empty",
+		"encode",
+		"encode gzip",
+		"options?",
+	} {
+		_, err := ParseColumnOptions(options)
+		assert.Error(t, err, options)
+	}
+}
@@ -0,0 +1,143 @@
+package transformer
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// OptionLength is the RequiredOptions entry of transformers whose columns need a length,
+// e.g. "(255)".
+const OptionLength = "length"
+
+// ConvertFunc turns a raw event property into the value written to a column. A nil value
+// with a nil error stands for NULL.
+type ConvertFunc func(string) (interface{}, error)
+
+// Transformer describes a column type that event properties can be transformed into.
+type Transformer struct {
+	// Name is the value of ColumnDefinition.Transformer that selects this transformer.
+	Name string
+	// SQLType is the Redshift type of the column, without any length.
+	SQLType string
+	// Mapping is true if the value is resolved through the column's SupportingColumns.
+	Mapping bool
+	// IPLookup is true if the value is looked up from the event's client IP.
+	IPLookup bool
+	// UserAgent is true if the value is converted from the event's user agent rather
+	// than from a property.
+	UserAgent bool
+	// RequiredOptions lists the column creation options the column must have, and is
+	// the only place a length is allowed.
+	RequiredOptions []string
+	// Convert converts a property value. It is nil if this package does not provide
+	// the conversion, or if it needs more than the property value. For mapping
+	// transformers it only handles values that need no mapping.
+	Convert ConvertFunc
+	// Sized returns the conversion for a column of the given length. It is set for
+	// transformers that require OptionLength.
+	Sized func(length int) (ConvertFunc, error)
+}
+
+// Requires returns true if option is one of the transformer's RequiredOptions.
+func (t Transformer) Requires(option string) bool {
+	for _, o := range t.RequiredOptions {
+		if o == option {
+			return true
+		}
+	}
+	return false
+}
+
+// Registry is a set of transformers, looked up by name. It is safe for concurrent use.
+type Registry struct {
+	mu           sync.RWMutex
+	transformers map[string]Transformer
+}
+
+// NewRegistry returns a Registry holding the built-in transformers.
+func NewRegistry() *Registry {
+	r := &Registry{transformers: make(map[string]Transformer)}
+	for _, t := range builtins() {
+		r.transformers[t.Name] = t
+	}
+	return r
+}
+
+// Register adds a transformer to the registry. It fails if the name is already taken.
+func (r *Registry) Register(t Transformer) error {
+	if t.Name == "" {
+		return errors.New("transformer has no name")
+	}
+	if t.SQLType == "" {
+		return fmt.Errorf("transformer %s has no SQL type", t.Name)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.transformers[t.Name]; ok {
+		return fmt.Errorf("transformer %s is already registered", t.Name)
+	}
+	r.transformers[t.Name] = t
+	return nil
+}
+
+// Lookup returns the transformer with the given name.
+func (r *Registry) Lookup(name string) (Transformer, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	t, ok := r.transformers[name]
+	return t, ok
+}
+
+// Names returns the sorted names of all registered transformers.
+func (r *Registry) Names() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	names := make([]string, 0, len(r.transformers))
+	for name := range r.transformers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// DefaultRegistry is the registry used by Register and Lookup.
+var DefaultRegistry = NewRegistry()
+
+// Register adds a transformer to DefaultRegistry.
+func Register(t Transformer) error {
+	return DefaultRegistry.Register(t)
+}
+
+// Lookup returns the transformer with the given name from DefaultRegistry.
+func Lookup(name string) (Transformer, bool) {
+	return DefaultRegistry.Lookup(name)
+}
+
+// builtins returns the transformers listed in ValidTransforms.
+func builtins() []Transformer {
+	varchar, _ := NewVarcharConverter(DefaultVarcharLength)
+	return []Transformer{
+		{Name: "bigint", SQLType: "BIGINT", Convert: ConvertBigint},
+		{Name: "bool", SQLType: "BOOLEAN", Convert: ConvertBool},
+		{Name: "float", SQLType: "FLOAT", Convert: ConvertFloat},
+		{Name: "int", SQLType: "INT", Convert: ConvertInt},
+		{Name: "ipAsn", SQLType: "VARCHAR(128)", IPLookup: true},
+		{Name: "ipAsnInteger", SQLType: "BIGINT", IPLookup: true},
+		{Name: "ipCity", SQLType: "VARCHAR(64)", IPLookup: true},
+		{Name: "ipCountry", SQLType: "VARCHAR(2)", IPLookup: true},
+		{Name: "ipRegion", SQLType: "VARCHAR(64)", IPLookup: true},
+		{
+			Name:            "varchar",
+			SQLType:         "VARCHAR",
+			RequiredOptions: []string{OptionLength},
+			Convert:         varchar,
+			Sized:           NewVarcharConverter,
+		},
+		{Name: "f@timestamp@unix", SQLType: "TIMESTAMP", Convert: ConvertTimestamp},
+		{Name: "f@timestamp@unix-utc", SQLType: "TIMESTAMP", Convert: ConvertTimestampUTC},
+		{Name: "userIDWithMapping", SQLType: "BIGINT", Mapping: true, Convert: ConvertBigint},
+	}
+}
@@ -0,0 +1,70 @@
+package transformer
+
+import (
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuiltinsMatchValidTransforms(t *testing.T) {
+	expected := append([]string(nil), ValidTransforms...)
+	sort.Strings(expected)
+	assert.Equal(t, expected, NewRegistry().Names())
+}
+
+func TestLookup(t *testing.T) {
+	varchar, ok := Lookup("varchar")
+	require.True(t, ok)
+	assert.Equal(t, "VARCHAR", varchar.SQLType)
+	assert.True(t, varchar.Requires(OptionLength))
+
+	mapping, ok := Lookup("userIDWithMapping")
+	require.True(t, ok)
+	assert.True(t, mapping.Mapping)
+	assert.False(t, mapping.Requires(OptionLength))
+
+	city, ok := Lookup("ipCity")
+	require.True(t, ok)
+	assert.True(t, city.IPLookup)
+
+	_, ok = Lookup("string")
+	assert.False(t, ok)
+}
+
+func TestRegister(t *testing.T) {
+	r := NewRegistry()
+	custom := Transformer{
+		Name:    "upper",
+		SQLType: "VARCHAR(16)",
+		Convert: func(s string) (interface{}, error) { return s, nil },
+	}
+	require.NoError(t, r.Register(custom))
+
+	found, ok := r.Lookup("upper")
+	require.True(t, ok)
+	assert.Equal(t, "VARCHAR(16)", found.SQLType)
+
+	_, ok = DefaultRegistry.Lookup("upper")
+	assert.False(t, ok, "registering on one registry must not affect another")
+
+	assert.Error(t, r.Register(custom), "duplicate registration worked")
+	assert.Error(t, r.Register(Transformer{Name: "bigint", SQLType: "BIGINT"}), "replacing a built-in worked")
+	assert.Error(t, r.Register(Transformer{SQLType: "BIGINT"}), "registering without a name worked")
+	assert.Error(t, r.Register(Transformer{Name: "untyped"}), "registering without a type worked")
+}
+
+func TestBuiltinConvert(t *testing.T) {
+	bigint, _ := Lookup("bigint")
+	v, err := bigint.Convert("42")
+	require.NoError(t, err)
+	assert.Equal(t, int64(42), v)
+
+	v, err = bigint.Convert("")
+	require.NoError(t, err)
+	assert.Nil(t, v)
+
+	_, err = bigint.Convert("forty-two")
+	assert.Error(t, err)
+}
@@ -2,6 +2,8 @@ package transformer
 
 var (
 	// ValidTransforms lists the types that a column in an event is allowed to have.
+	// It is kept for compatibility; the built-in transformers are described in detail
+	// by NewRegistry, which also accepts custom ones.
 	ValidTransforms = []string{
 		"bigint",
 		"bool",
@@ -0,0 +1,73 @@
+package useragent
+
+import (
+	"fmt"
+
+	"github.com/twitchscience/scoop_protocol/transformer"
+)
+
+// varcharFields extracts the value of each varchar user agent transformer from an Info,
+// truncated to the column's length.
+var varcharFields = []struct {
+	name   string
+	length int
+	value  func(Info) string
+}{
+	{"uaBrowser", 64, func(i Info) string { return i.Browser }},
+	{"uaBrowserVersion", 32, func(i Info) string { return i.BrowserVersion }},
+	{"uaOS", 64, func(i Info) string { return i.OS }},
+	{"uaOSVersion", 32, func(i Info) string { return i.OSVersion }},
+	{"uaDeviceClass", 16, func(i Info) string { return i.DeviceClass }},
+}
+
+// Register adds the user agent transformers, parsing with DefaultParser, to r.
+func Register(r *transformer.Registry) error {
+	return DefaultParser.Register(r)
+}
+
+// RegisterDefault adds the user agent transformers, parsing with DefaultParser, to
+// transformer.DefaultRegistry. scoop_protocol's Config.Validate and the redshift package
+// look transformers up there, so programs that accept configs using ua* columns must
+// call it once at startup.
+func RegisterDefault() error {
+	return Register(transformer.DefaultRegistry)
+}
+
+// Register adds the user agent transformers to r: uaBrowser, uaBrowserVersion, uaOS,
+// uaOSVersion, uaDeviceClass and uaBot. Their columns are filled from the event's
+// UserAgent, and an empty user agent converts to NULL.
+func (p *Parser) Register(r *transformer.Registry) error {
+	for _, f := range varcharFields {
+		value := f.value
+		varchar, err := transformer.NewVarcharConverter(f.length)
+		if err != nil {
+			return err
+		}
+		err = r.Register(transformer.Transformer{
+			Name:      f.name,
+			SQLType:   fmt.Sprintf("VARCHAR(%d)", f.length),
+			UserAgent: true,
+			Convert: func(ua string) (interface{}, error) {
+				if ua == "" {
+					return nil, nil
+				}
+				return varchar(value(p.Parse(ua)))
+			},
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return r.Register(transformer.Transformer{
+		Name:      "uaBot",
+		SQLType:   "BOOLEAN",
+		UserAgent: true,
+		Convert: func(ua string) (interface{}, error) {
+			if ua == "" {
+				return nil, nil
+			}
+			return p.Parse(ua).Bot, nil
+		},
+	})
+}
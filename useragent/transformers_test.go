@@ -0,0 +1,64 @@
+package useragent
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/twitchscience/scoop_protocol/scoop_protocol"
+	"github.com/twitchscience/scoop_protocol/transformer"
+)
+
+func TestRegister(t *testing.T) {
+	r := transformer.NewRegistry()
+	require.NoError(t, Register(r))
+	assert.Error(t, Register(r), "registered twice")
+
+	ua := "Mozilla/5.0 (iPhone; CPU iPhone OS 17_1 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.1 Mobile/15E148 Safari/604.1"
+	expected := map[string][]interface{}{
+		// ua, "", "curl/8.4.0"
+		"uaBrowser":        {"Safari", nil, "curl"},
+		"uaBrowserVersion": {"17.1", nil, "8.4.0"},
+		"uaOS":             {"iOS", nil, nil},
+		"uaOSVersion":      {"17.1", nil, nil},
+		"uaDeviceClass":    {Mobile, nil, Bot},
+		"uaBot":            {false, nil, true},
+	}
+	for name, values := range expected {
+		tr, ok := r.Lookup(name)
+		require.True(t, ok, name)
+		assert.True(t, tr.UserAgent)
+
+		convert, err := tr.ConverterFor("")
+		require.NoError(t, err)
+		for i, s := range []string{ua, "", "curl/8.4.0"} {
+			v, err := convert(s)
+			assert.NoError(t, err)
+			assert.Equal(t, values[i], v, "%s(%q)", name, s)
+		}
+	}
+
+	// values are cut to fit their columns
+	tr, _ := r.Lookup("uaBrowserVersion")
+	assert.Equal(t, "VARCHAR(32)", tr.SQLType)
+	v, err := tr.Convert("curl/" + strings.Repeat("9", 100))
+	assert.NoError(t, err)
+	assert.Equal(t, strings.Repeat("9", 32), v)
+
+	_, ok := transformer.Lookup("uaBrowser")
+	assert.False(t, ok, "registering changed the default registry")
+}
+
+func TestRegisterDefault(t *testing.T) {
+	defer func(r *transformer.Registry) { transformer.DefaultRegistry = r }(transformer.DefaultRegistry)
+	transformer.DefaultRegistry = transformer.NewRegistry()
+
+	cfg := scoop_protocol.Config{EventName: "e", Columns: []scoop_protocol.ColumnDefinition{
+		{InboundName: "user_agent", OutboundName: "browser", Transformer: "uaBrowser"},
+		{InboundName: "user_agent", OutboundName: "bot", Transformer: "uaBot"},
+	}}
+	assert.Error(t, cfg.Validate(), "ua transformers were known before registering")
+	require.NoError(t, RegisterDefault())
+	assert.NoError(t, cfg.Validate())
+}
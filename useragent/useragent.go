@@ -0,0 +1,162 @@
+// Package useragent parses the user agents of spade events into the browser, operating
+// system and class of device that sent them, and provides transformers for their parts.
+// RegisterDefault makes the transformers known to config validation and DDL.
+package useragent
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// The device classes a user agent can have.
+const (
+	Desktop = "desktop"
+	Mobile  = "mobile"
+	Tablet  = "tablet"
+	TV      = "tv"
+	Console = "console"
+	Bot     = "bot"
+)
+
+// Info is what a user agent says about the client that sent it. Parts that could not be
+// found are empty.
+type Info struct {
+	Browser        string
+	BrowserVersion string
+	OS             string
+	OSVersion      string
+	DeviceClass    string
+	// Bot is true for crawlers, scripts and headless browsers. Browser then holds the
+	// bot's name and DeviceClass is Bot.
+	Bot bool
+}
+
+//go:embed rules.json
+var defaultRules []byte
+
+// rules is the format of rules.json. Each list is tried in order and the first rule
+// whose pattern matches wins. Versions come from a pattern's first group, if it has one.
+type rules struct {
+	Bots     []namedRule  `json:"bots"`
+	Browsers []namedRule  `json:"browsers"`
+	OS       []namedRule  `json:"os"`
+	Devices  []deviceRule `json:"devices"`
+}
+
+type namedRule struct {
+	Name    string `json:"name"`
+	Pattern string `json:"pattern"`
+	re      *regexp.Regexp
+}
+
+type deviceRule struct {
+	Class   string `json:"class"`
+	Pattern string `json:"pattern"`
+	re      *regexp.Regexp
+}
+
+// cacheSize is the number of user agents a Parser remembers. There are few distinct
+// user agents compared to events, so the cache is simply emptied when it fills up.
+const cacheSize = 4096
+
+// Parser parses user agents with a set of rules. It is safe for concurrent use.
+type Parser struct {
+	rules rules
+
+	mu    sync.Mutex
+	cache map[string]Info
+}
+
+// NewParser returns a Parser for rules in the JSON format of the embedded rules.json.
+func NewParser(rulesJSON []byte) (*Parser, error) {
+	var r rules
+	if err := json.Unmarshal(rulesJSON, &r); err != nil {
+		return nil, fmt.Errorf("error parsing user agent rules: %v", err)
+	}
+	for _, list := range [][]namedRule{r.Bots, r.Browsers, r.OS} {
+		for i := range list {
+			re, err := regexp.Compile(list[i].Pattern)
+			if err != nil {
+				return nil, fmt.Errorf("rule %s: %v", list[i].Name, err)
+			}
+			list[i].re = re
+		}
+	}
+	for i := range r.Devices {
+		re, err := regexp.Compile(r.Devices[i].Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("rule %s: %v", r.Devices[i].Class, err)
+		}
+		r.Devices[i].re = re
+	}
+	return &Parser{rules: r, cache: make(map[string]Info)}, nil
+}
+
+// DefaultParser parses with the embedded rules.
+var DefaultParser = func() *Parser {
+	p, err := NewParser(defaultRules)
+	if err != nil {
+		panic(err)
+	}
+	return p
+}()
+
+// Parse parses a user agent with DefaultParser.
+func Parse(ua string) Info {
+	return DefaultParser.Parse(ua)
+}
+
+// Parse returns what the user agent says about its client.
+func (p *Parser) Parse(ua string) Info {
+	ua = strings.TrimSpace(ua)
+	if ua == "" {
+		return Info{}
+	}
+	p.mu.Lock()
+	info, ok := p.cache[ua]
+	p.mu.Unlock()
+	if ok {
+		return info
+	}
+
+	info.OS, info.OSVersion = match(p.rules.OS, ua)
+	if info.Browser, info.BrowserVersion = match(p.rules.Bots, ua); info.Browser != "" {
+		info.Bot = true
+		info.DeviceClass = Bot
+	} else {
+		info.Browser, info.BrowserVersion = match(p.rules.Browsers, ua)
+		for _, rule := range p.rules.Devices {
+			if rule.re.MatchString(ua) {
+				info.DeviceClass = rule.Class
+				break
+			}
+		}
+	}
+
+	p.mu.Lock()
+	if len(p.cache) >= cacheSize {
+		p.cache = make(map[string]Info)
+	}
+	p.cache[ua] = info
+	p.mu.Unlock()
+	return info
+}
+
+// match returns the name of the first rule matching ua, and the version it found.
+func match(list []namedRule, ua string) (name, version string) {
+	for _, rule := range list {
+		m := rule.re.FindStringSubmatch(ua)
+		if m == nil {
+			continue
+		}
+		if len(m) > 1 {
+			version = strings.Replace(m[1], "_", ".", -1)
+		}
+		return rule.Name, version
+	}
+	return "", ""
+}
@@ -0,0 +1,113 @@
+package useragent
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		ua       string
+		expected Info
+	}{
+		{
+			"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/118.0.0.0 Safari/537.36",
+			Info{Browser: "Chrome", BrowserVersion: "118.0.0.0", OS: "Windows", OSVersion: "10.0", DeviceClass: Desktop},
+		},
+		{
+			"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/118.0.0.0 Safari/537.36 Edg/118.0.2088.76",
+			Info{Browser: "Edge", BrowserVersion: "118.0.2088.76", OS: "Windows", OSVersion: "10.0", DeviceClass: Desktop},
+		},
+		{
+			"Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.1 Safari/605.1.15",
+			Info{Browser: "Safari", BrowserVersion: "17.1", OS: "macOS", OSVersion: "10.15.7", DeviceClass: Desktop},
+		},
+		{
+			"Mozilla/5.0 (X11; Ubuntu; Linux x86_64; rv:119.0) Gecko/20100101 Firefox/119.0",
+			Info{Browser: "Firefox", BrowserVersion: "119.0", OS: "Linux", DeviceClass: Desktop},
+		},
+		{
+			"Mozilla/5.0 (iPhone; CPU iPhone OS 17_1 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.1 Mobile/15E148 Safari/604.1",
+			Info{Browser: "Safari", BrowserVersion: "17.1", OS: "iOS", OSVersion: "17.1", DeviceClass: Mobile},
+		},
+		{
+			"Mozilla/5.0 (iPad; CPU OS 16_6 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) CriOS/118.0.5993.92 Mobile/15E148 Safari/604.1",
+			Info{Browser: "Chrome", BrowserVersion: "118.0.5993.92", OS: "iOS", OSVersion: "16.6", DeviceClass: Tablet},
+		},
+		{
+			"Mozilla/5.0 (Linux; Android 13; SM-S911B) AppleWebKit/537.36 (KHTML, like Gecko) SamsungBrowser/23.0 Chrome/115.0.0.0 Mobile Safari/537.36",
+			Info{Browser: "Samsung Internet", BrowserVersion: "23.0", OS: "Android", OSVersion: "13", DeviceClass: Mobile},
+		},
+		{
+			"Mozilla/5.0 (Linux; Android 12; SM-X700) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/118.0.0.0 Safari/537.36",
+			Info{Browser: "Chrome", BrowserVersion: "118.0.0.0", OS: "Android", OSVersion: "12", DeviceClass: Tablet},
+		},
+		{
+			"Mozilla/5.0 (Windows NT 6.1; WOW64; Trident/7.0; rv:11.0) like Gecko",
+			Info{Browser: "Internet Explorer", BrowserVersion: "11.0", OS: "Windows", OSVersion: "6.1", DeviceClass: Desktop},
+		},
+		{
+			"Mozilla/5.0 (Windows NT 10.0; Win64; x64; Xbox; Xbox One) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/70.0.3538.102 Safari/537.36 Edge/18.19041",
+			Info{Browser: "Edge", BrowserVersion: "18.19041", OS: "Xbox", DeviceClass: Console},
+		},
+		{
+			"Mozilla/5.0 (PlayStation 5 3.20) AppleWebKit/605.1.15 (KHTML, like Gecko)",
+			Info{OS: "PlayStation", DeviceClass: Console},
+		},
+		{
+			"Mozilla/5.0 (Linux; Android 9; AFTMM Build/PS7233) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/95.0.4638.74 Mobile Safari/537.36",
+			Info{Browser: "Chrome", BrowserVersion: "95.0.4638.74", OS: "Android", OSVersion: "9", DeviceClass: TV},
+		},
+		{
+			"Mozilla/5.0 (compatible; Googlebot/2.1; +http://www.google.com/bot.html)",
+			Info{Browser: "Googlebot", BrowserVersion: "2.1", DeviceClass: Bot, Bot: true},
+		},
+		{
+			"Mozilla/5.0 (X11; Linux x86_64) AppleWebKit/537.36 (KHTML, like Gecko) HeadlessChrome/118.0.5993.70 Safari/537.36",
+			Info{Browser: "Headless Chrome", BrowserVersion: "118.0.5993.70", OS: "Linux", DeviceClass: Bot, Bot: true},
+		},
+		{
+			"curl/8.4.0",
+			Info{Browser: "curl", BrowserVersion: "8.4.0", DeviceClass: Bot, Bot: true},
+		},
+		{
+			"SomeCrawler/1.0 (+https://example.com)",
+			Info{Browser: "Bot", DeviceClass: Bot, Bot: true},
+		},
+		{"", Info{}},
+		{"definitely not a user agent", Info{}},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.expected, Parse(tt.ua), tt.ua)
+		// the second parse comes from the cache
+		assert.Equal(t, tt.expected, Parse(tt.ua), tt.ua)
+	}
+}
+
+func TestNewParser(t *testing.T) {
+	p, err := NewParser([]byte(`{
+		"browsers": [{"name": "Twitch", "pattern": "Twitch/([\\d.]+)"}],
+		"devices": [{"class": "mobile", "pattern": "Twitch/"}]
+	}`))
+	require.NoError(t, err)
+	assert.Equal(t, Info{Browser: "Twitch", BrowserVersion: "15.2", DeviceClass: Mobile}, p.Parse("Twitch/15.2"))
+
+	_, err = NewParser([]byte(`{"os": [{"name": "broken", "pattern": "("}]}`))
+	assert.Error(t, err)
+	_, err = NewParser([]byte(`{"devices": [{"class": "broken", "pattern": "("}]}`))
+	assert.Error(t, err)
+	_, err = NewParser([]byte(`[`))
+	assert.Error(t, err)
+}
+
+func TestParserCacheBounded(t *testing.T) {
+	p, err := NewParser(defaultRules)
+	require.NoError(t, err)
+	for i := 0; i < cacheSize*2; i++ {
+		p.Parse(fmt.Sprintf("curl/%d", i))
+	}
+	assert.True(t, len(p.cache) <= cacheSize)
+}